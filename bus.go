@@ -0,0 +1,158 @@
+package framereader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultAging is the aging interval used by NewBus.
+const DefaultAging = time.Second
+
+// Priority is the priority of a transaction on a Bus. Transactions of higher priority are started first.
+type Priority int
+
+const (
+	// PriorityLow is used for background transactions, e.g. probing devices.
+	PriorityLow Priority = -1
+
+	// PriorityNormal is the priority used by Transact.
+	PriorityNormal Priority = 0
+
+	// PriorityHigh is used for transactions, which must not wait for others, e.g. commands of an operator.
+	PriorityHigh Priority = 1
+)
+
+// Bus serializes the transactions of concurrent goroutines sharing one line, e.g. polling different slaves
+// on a RS-485 bus. Each caller receives the response to its own request.
+//
+// Pending transactions are started by priority. To keep transactions of low priority from starving,
+// the priority of a pending transaction is raised by one per aging interval it waits.
+// Transactions of the same priority are started in the order of their arrival.
+type Bus struct {
+	transactor Transactor
+	clock      Clock
+	aging      time.Duration
+
+	mu      sync.Mutex    // guards the following fields
+	busy    bool          // a transaction is running
+	pending []*busRequest // in the order of arrival
+}
+
+// busRequest is a pending transaction. ready is closed, when the transaction may start.
+type busRequest struct {
+	priority Priority
+	queued   time.Time
+	ready    chan struct{}
+}
+
+// BusConfig is used to configure a Bus.
+type BusConfig struct {
+	// Aging is the interval, after which the priority of a pending transaction is raised by one.
+	// If Aging is 0, DefaultAging is used.
+	Aging time.Duration
+
+	// Clock is the source of time for the aging. If Clock is nil, the real time is used.
+	Clock Clock
+}
+
+// NewBus creates a bus, which serializes the transactions of transactor, e.g. a *ReadWriteCloser.
+func NewBus(transactor Transactor) *Bus {
+	return NewBusConfig(transactor, BusConfig{})
+}
+
+// NewBusConfig creates a bus using config.
+func NewBusConfig(transactor Transactor, config BusConfig) *Bus {
+	if config.Aging <= 0 {
+		config.Aging = DefaultAging
+	}
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+	return &Bus{transactor: transactor, clock: config.Clock, aging: config.Aging}
+}
+
+// Transact runs a transaction of PriorityNormal, see TransactPriority.
+func (b *Bus) Transact(ctx context.Context, request []byte, match func(Frame) bool) (Frame, error) {
+	return b.TransactPriority(ctx, PriorityNormal, request, match)
+}
+
+// TransactPriority waits, until the bus is free and no pending transaction has a higher priority,
+// and then runs the transaction. If ctx is done, before the transaction is started, ctx.Err() is returned,
+// so the deadline of ctx limits the time waiting for the bus and the transaction itself.
+func (b *Bus) TransactPriority(ctx context.Context, priority Priority, request []byte, match func(Frame) bool) (Frame, error) {
+	if err := b.acquire(ctx, priority); err != nil {
+		return Frame{}, err
+	}
+	defer b.release()
+
+	return b.transactor.Transact(ctx, request, match)
+}
+
+// acquire waits, until the transaction may start.
+func (b *Bus) acquire(ctx context.Context, priority Priority) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if !b.busy {
+		b.busy = true
+		b.mu.Unlock()
+		return nil
+	}
+
+	r := &busRequest{priority: priority, queued: b.clock.Now(), ready: make(chan struct{})}
+	b.pending = append(b.pending, r)
+	b.mu.Unlock()
+
+	select {
+	case <-r.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	if isClosed(r.ready) {
+		// the bus has been granted meanwhile
+		b.mu.Unlock()
+		b.release()
+		return ctx.Err()
+	}
+	for i, p := range b.pending {
+		if p == r {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+	return ctx.Err()
+}
+
+// release starts the pending transaction of the highest priority or frees the bus.
+func (b *Bus) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		b.busy = false
+		return
+	}
+
+	now := b.clock.Now()
+	next := 0
+	for i, r := range b.pending {
+		if b.priority(r, now) > b.priority(b.pending[next], now) {
+			next = i
+		}
+	}
+
+	r := b.pending[next]
+	b.pending = append(b.pending[:next], b.pending[next+1:]...)
+	close(r.ready)
+}
+
+// priority returns the priority of r raised by the aging.
+func (b *Bus) priority(r *busRequest, now time.Time) int {
+	return int(r.priority) + int(now.Sub(r.queued)/b.aging)
+}
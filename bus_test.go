@@ -0,0 +1,191 @@
+package framereader
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+// blockingTransactor records the requests and blocks each transaction, until release receives a value.
+type blockingTransactor struct {
+	mu       sync.Mutex
+	requests []byte
+	started  chan struct{}
+	release  chan struct{}
+	active   int32
+}
+
+func newBlockingTransactor() *blockingTransactor {
+	return &blockingTransactor{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (t *blockingTransactor) Transact(_ context.Context, request []byte, _ func(Frame) bool) (Frame, error) {
+	if atomic.AddInt32(&t.active, 1) != 1 {
+		panic("concurrent transactions")
+	}
+	defer atomic.AddInt32(&t.active, -1)
+
+	t.mu.Lock()
+	t.requests = append(t.requests, request[0])
+	t.mu.Unlock()
+
+	t.started <- struct{}{}
+	<-t.release
+	return Frame{Data: request}, nil
+}
+
+// waitPending waits, until n transactions are pending.
+func waitPending(b *Bus, n int) {
+	for {
+		b.mu.Lock()
+		pending := len(b.pending)
+		b.mu.Unlock()
+		if pending == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBusPriority(t *testing.T) {
+	transactor := newBlockingTransactor()
+	clock := framereadertest.NewClock(epoch)
+	bus := NewBusConfig(transactor, BusConfig{Aging: time.Second, Clock: clock})
+
+	var wg sync.WaitGroup
+	transact := func(id byte, priority Priority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := bus.TransactPriority(context.Background(), priority, []byte{id}, MatchAll)
+			if err != nil || f.Data[0] != id {
+				t.Errorf("%v: expected own response, got %v (err: %v)", id, f.Data, err)
+			}
+		}()
+	}
+
+	transact(1, PriorityNormal)
+	<-transactor.started
+
+	transact(2, PriorityLow)
+	waitPending(bus, 1)
+	transact(3, PriorityNormal)
+	waitPending(bus, 2)
+	transact(4, PriorityHigh)
+	waitPending(bus, 3)
+	transact(5, PriorityNormal)
+	waitPending(bus, 4)
+
+	for i := 0; i < 4; i++ {
+		transactor.release <- struct{}{}
+		<-transactor.started
+	}
+	transactor.release <- struct{}{}
+	wg.Wait()
+
+	if exp := []byte{1, 4, 3, 5, 2}; !reflect.DeepEqual(transactor.requests, exp) {
+		t.Errorf("expected order %v, got %v", exp, transactor.requests)
+	}
+}
+
+func TestBusAging(t *testing.T) {
+	transactor := newBlockingTransactor()
+	clock := framereadertest.NewClock(epoch)
+	bus := NewBusConfig(transactor, BusConfig{Aging: time.Second, Clock: clock})
+
+	var wg sync.WaitGroup
+	transact := func(id byte, priority Priority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.TransactPriority(context.Background(), priority, []byte{id}, MatchAll)
+		}()
+	}
+
+	transact(1, PriorityNormal)
+	<-transactor.started
+
+	// the low priority transaction waits longer than the aging of two priority levels
+	transact(2, PriorityLow)
+	waitPending(bus, 1)
+	clock.Advance(3 * time.Second)
+	transact(3, PriorityHigh)
+	waitPending(bus, 2)
+
+	for i := 0; i < 2; i++ {
+		transactor.release <- struct{}{}
+		<-transactor.started
+	}
+	transactor.release <- struct{}{}
+	wg.Wait()
+
+	if exp := []byte{1, 2, 3}; !reflect.DeepEqual(transactor.requests, exp) {
+		t.Errorf("expected order %v, got %v", exp, transactor.requests)
+	}
+}
+
+func TestBusDeadline(t *testing.T) {
+	transactor := newBlockingTransactor()
+	bus := NewBus(transactor)
+
+	go bus.Transact(context.Background(), []byte{1}, MatchAll)
+	<-transactor.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := bus.Transact(ctx, []byte{2}, MatchAll); err != context.DeadlineExceeded {
+		t.Error("expected deadline exceeded, got: ", err)
+	}
+	waitPending(bus, 0)
+
+	transactor.release <- struct{}{}
+
+	// the bus is free again
+	done := make(chan struct{})
+	go func() {
+		bus.Transact(context.Background(), []byte{3}, MatchAll)
+		close(done)
+	}()
+	<-transactor.started
+	transactor.release <- struct{}{}
+	<-done
+
+	if exp := []byte{1, 3}; !reflect.DeepEqual(transactor.requests, exp) {
+		t.Errorf("expected canceled transaction not to be started, got %v", transactor.requests)
+	}
+}
+
+func TestBusReadWriteCloser(t *testing.T) {
+	var _ Transactor = (*Bus)(nil)
+
+	master, slave := net.Pipe()
+	defer slave.Close()
+	rwc := NewReadWriteCloserConfig(master, Config{Timeout: time.Second, InterFrameDelay: 5 * time.Millisecond})
+	defer rwc.Close()
+	bus := NewBus(rwc)
+
+	go device(slave, func(request []byte) [][]byte {
+		return [][]byte{{request[0], 0xff}}
+	})
+
+	var wg sync.WaitGroup
+	for i := byte(1); i <= 4; i++ {
+		wg.Add(1)
+		go func(id byte) {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				f, err := bus.TransactPriority(context.Background(), Priority(id%3-1), []byte{id}, MatchAll)
+				if err != nil || f.Data[0] != id {
+					t.Errorf("%v: expected own response, got %v (err: %v)", id, f.Data, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,46 @@
+package framereader
+
+import (
+	"time"
+)
+
+// Clock is the source of time of a Reader. It can be replaced to control the timing in tests,
+// see package framereadertest.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc calls f after d has elapsed. stop cancels the call,
+	// it returns false, if f has already been called or the call has been stopped.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// realClock is the Clock based on package time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// timer is a single shot timer based on a Clock. C receives a value, when the timer expires.
+type timer struct {
+	C    chan struct{}
+	stop func() bool
+}
+
+func newTimer(clock Clock, d time.Duration) *timer {
+	t := &timer{C: make(chan struct{}, 1)}
+	t.stop = clock.AfterFunc(d, func() {
+		t.C <- struct{}{}
+	})
+	return t
+}
+
+// Stop prevents the timer from firing.
+func (t *timer) Stop() bool {
+	return t.stop()
+}
@@ -0,0 +1,159 @@
+package framereader
+
+import (
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frameReaderGoroutines returns the number of running frame reader goroutines.
+func frameReaderGoroutines() int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	count := 0
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(g, "framereader.(*Reader).framereader") {
+			count++
+		}
+	}
+	return count
+}
+
+// checkGoroutineLeaks fails the test, if more frame reader goroutines are running than before.
+// Goroutines of other tests, which never close their readers, are taken into account by before.
+func checkGoroutineLeaks(t *testing.T, before int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		n := frameReaderGoroutines()
+		if n <= before {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("%v frame reader goroutine(s) leaked", n-before)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// closeWithin fails the test, if close doesn't return within d.
+func closeWithin(t *testing.T, d time.Duration, close func() error) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		close()
+		done <- struct{}{}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(d):
+		t.Fatal("close didn't return")
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	before := frameReaderGoroutines()
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	reader := NewReader(conn, time.Second, 10*time.Millisecond)
+
+	go peer.Write([]byte{1, 2, 3})
+	if _, err := reader.Read(make([]byte, 10)); err != nil {
+		t.Error("read failed: ", err)
+	}
+
+	errc := make(chan error)
+	go func() {
+		_, err := reader.Read(make([]byte, 10))
+		errc <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	closeWithin(t, time.Second, reader.Close)
+
+	if err := <-errc; err != ErrClosed {
+		t.Error("expected pending read to return ErrClosed, got: ", err)
+	}
+	if _, err := reader.Read(make([]byte, 10)); err != ErrClosed {
+		t.Error("expected ErrClosed, got: ", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Error("expected second close to succeed, got: ", err)
+	}
+
+	// the underlying reader is still usable
+	go peer.Write([]byte{4})
+	if n, err := conn.Read(make([]byte, 10)); n != 1 || err != nil {
+		t.Error("expected read deadline to be reset by close, got: ", n, err)
+	}
+
+	checkGoroutineLeaks(t, before)
+}
+
+func TestReadCloserClose(t *testing.T) {
+	before := frameReaderGoroutines()
+
+	pr, pw := io.Pipe()
+	rc := NewReadCloser(pr, time.Second, 10*time.Millisecond)
+
+	// frames, which are never read, must not block the frame reader on close
+	go func() {
+		for i := 0; i < 10; i++ {
+			if _, err := pw.Write([]byte{byte(i)}); err != nil {
+				return
+			}
+			time.Sleep(15 * time.Millisecond)
+		}
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	closeWithin(t, time.Second, rc.Close)
+
+	if _, err := rc.Read(make([]byte, 10)); err != ErrClosed {
+		t.Error("expected ErrClosed, got: ", err)
+	}
+
+	checkGoroutineLeaks(t, before)
+}
+
+func TestReadWriteCloserClose(t *testing.T) {
+	before := frameReaderGoroutines()
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	rwc := NewReadWriteCloser(conn, time.Second, 10*time.Millisecond)
+
+	go func() {
+		buf := make([]byte, 10)
+		n, _ := peer.Read(buf)
+		peer.Write(buf[:n])
+	}()
+
+	if _, err := rwc.Write([]byte("ping")); err != nil {
+		t.Error("write failed: ", err)
+	}
+
+	buf := make([]byte, 10)
+	if n, err := rwc.Read(buf); err != nil || string(buf[:n]) != "ping" {
+		t.Errorf("expected echo, got %q (err: %v)", buf[:n], err)
+	}
+
+	closeWithin(t, time.Second, rwc.Close)
+	checkGoroutineLeaks(t, before)
+}
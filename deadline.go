@@ -0,0 +1,79 @@
+package framereader
+
+import (
+	"sync"
+	"time"
+)
+
+// deadline is used to handle read and write deadlines in the style of net.Conn.
+// The cancel channel is closed, if the deadline is exceeded.
+type deadline struct {
+	mu     sync.Mutex // guards all fields
+	clock  Clock
+	t      time.Time
+	stop   func() bool // stops the timer closing cancel
+	cancel chan struct{}
+}
+
+func newDeadline(clock Clock) *deadline {
+	return &deadline{clock: clock, cancel: make(chan struct{})}
+}
+
+// set sets the point in time, when the deadline is exceeded.
+// A zero value for t means no deadline.
+func (d *deadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop != nil && !d.stop() {
+		<-d.cancel // wait for the timer callback to finish and close cancel
+	}
+	d.stop = nil
+	d.t = t
+
+	closed := isClosed(d.cancel)
+	if t.IsZero() {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := t.Sub(d.clock.Now()); dur > 0 {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.stop = d.clock.AfterFunc(dur, func() {
+			close(cancel)
+		})
+		return
+	}
+
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+// wait returns a channel, which is closed when the deadline is exceeded.
+func (d *deadline) wait() chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+// time returns the deadline. The zero value means no deadline.
+func (d *deadline) time() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.t
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
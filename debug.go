@@ -79,12 +79,18 @@ var (
 	debuglog   *log.Logger
 	tracelog   *log.Logger
 	fatallog   *log.Logger
+
+	// debugWriter is the writer passed to SetDebug.
+	debugWriter io.Writer
 )
 
 func init() {
 	SetDebug(os.Stderr, Standard)
 }
 
+// SetDebug configures the package loggers for the levels in flag.
+// The package loggers are used by all readers, which have no Logger configured.
+// The levels of a single reader can be extended at runtime by SetLogLevel.
 func SetDebug(w io.Writer, flag int) {
 	warningHandle := ioutil.Discard
 	infoHandle := ioutil.Discard
@@ -94,10 +100,10 @@ func SetDebug(w io.Writer, flag int) {
 	fatalHandle := ioutil.Discard
 
 	if flag&Info != 0 {
-		warningHandle = w
+		infoHandle = w
 	}
 	if flag&Warning != 0 {
-		infoHandle = w
+		warningHandle = w
 	}
 	if flag&Error != 0 {
 		errorHandle = w
@@ -112,10 +118,11 @@ func SetDebug(w io.Writer, flag int) {
 		fatalHandle = w
 	}
 
-	infolog = log.New(warningHandle, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile|log.Lmsgprefix)
-	warninglog = log.New(infoHandle, "WARNING: ", log.Ldate|log.Ltime|log.Lshortfile|log.Lmsgprefix)
-	errorlog = log.New(errorHandle, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile|log.Lmsgprefix)
-	debuglog = log.New(debugHandle, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile|log.Lmsgprefix)
-	tracelog = log.New(traceHandle, "TRACE: ", log.Ldate|log.Ltime|log.Lshortfile|log.Lmsgprefix)
-	fatallog = log.New(fatalHandle, "FATAL: ", log.Ldate|log.Ltime|log.Llongfile|log.Lmsgprefix)
+	debugWriter = w
+	infolog = log.New(infoHandle, "INFO: ", log.Ldate|log.Ltime|log.Lmsgprefix)
+	warninglog = log.New(warningHandle, "WARNING: ", log.Ldate|log.Ltime|log.Lmsgprefix)
+	errorlog = log.New(errorHandle, "ERROR: ", log.Ldate|log.Ltime|log.Lmsgprefix)
+	debuglog = log.New(debugHandle, "DEBUG: ", log.Ldate|log.Ltime|log.Lmsgprefix)
+	tracelog = log.New(traceHandle, "TRACE: ", log.Ldate|log.Ltime|log.Lmsgprefix)
+	fatallog = log.New(fatalHandle, "FATAL: ", log.Ldate|log.Ltime|log.Lmsgprefix)
 }
@@ -0,0 +1,49 @@
+package framereader
+
+import (
+	"errors"
+	"os"
+)
+
+var (
+	// ErrTimeout is returned by Read, if no frame is received within the overall timeout.
+	// It implements net.Error and errors.Is(ErrTimeout, os.ErrDeadlineExceeded) reports true.
+	ErrTimeout error = timeoutError{}
+
+	// ErrClosed is returned by Read, if the reader has been closed.
+	ErrClosed = errors.New("framereader: reader closed")
+
+	// ErrFrameTooLarge is returned by Read together with the partial frame,
+	// if the frame exceeds the max frame size and the overflow policy is OverflowError.
+	ErrFrameTooLarge = errors.New("framereader: frame too large")
+
+	// ErrChecksum is returned by Read together with the frame,
+	// if the checksum of the frame is invalid and the checksum policy is ChecksumError.
+	ErrChecksum = errors.New("framereader: invalid checksum")
+
+	// ErrInvalidLength is returned by Read together with the received data,
+	// if the length field of a LengthFramer announces a frame shorter than its header.
+	ErrInvalidLength = errors.New("framereader: invalid length field")
+
+	// ErrEncoding is returned by Read together with the undecoded frame, if a byte stuffed frame can't be decoded.
+	ErrEncoding = errors.New("framereader: invalid encoding")
+)
+
+// timeoutError is the type of ErrTimeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "framereader: timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// Is reports true for os.ErrDeadlineExceeded, so timeouts can be handled like deadlines of net.Conn.
+func (timeoutError) Is(target error) bool {
+	return target == os.ErrDeadlineExceeded
+}
+
+// isTimeout reports whether err is a timeout error of the underlying reader.
+// Such errors are returned by some serial port implementations, if no data is received.
+func isTimeout(err error) bool {
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout() || errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,107 @@
+package framereader
+
+import (
+	"time"
+)
+
+// Frame is a frame received by the Reader, including the timing measured while receiving it.
+// The timing is measured per chunk of data returned by the underlying io.Reader,
+// so all bytes of a chunk share the same timestamp.
+type Frame struct {
+	// Data is the payload of the frame.
+	Data []byte
+
+	// Start is the time, the first byte of the frame has been received.
+	Start time.Time
+
+	// End is the time, the last byte of the frame has been received.
+	End time.Time
+
+	// MaxCharDelay is the max inter character delay measured within the frame.
+	MaxCharDelay time.Duration
+
+	// Idle is the idle time of the line before the first byte of the frame has been received.
+	Idle time.Duration
+
+	// Truncated reports that the frame exceeded the max frame size and has been truncated.
+	Truncated bool
+}
+
+// OverflowPolicy defines how frames exceeding the max frame size are handled.
+type OverflowPolicy int
+
+const (
+	// OverflowTruncate cuts the frame at the max frame size and drops the remainder of the frame.
+	OverflowTruncate OverflowPolicy = iota
+
+	// OverflowSplit delivers the frame as consecutive frames of max frame size.
+	// A frame exceeding the max frame size before its end is received is truncated instead,
+	// if the framer can't find the rest of the frame, see Resyncer, or the frame is encoded, see Encoder.
+	OverflowSplit
+
+	// OverflowError cuts the frame like OverflowTruncate, and Read returns the partial frame together with ErrFrameTooLarge.
+	OverflowError
+)
+
+// frame is a frame passed from the frame reader to Read.
+type frame struct {
+	Frame
+	err error
+}
+
+// chunk is the arrival information of a chunk of data in the frame buffer.
+type chunk struct {
+	size    int           // number of bytes of the chunk, which are not consumed yet
+	at      time.Time     // arrival time of the chunk
+	gap     time.Duration // time between the arrival of the previous chunk and this chunk
+	partial bool          // a part of the chunk is already consumed
+}
+
+// framebuffer holds the received data, which is not consumed by a frame yet.
+type framebuffer struct {
+	data      []byte
+	chunks    []chunk
+	last      time.Time // arrival time of the last chunk
+	discard   bool      // the remainder of an oversized frame of unknown size is dropped
+	remainder int       // number of bytes of an oversized frame of known size still to be received
+}
+
+// append adds a chunk of data received at t.
+func (b *framebuffer) append(data []byte, t time.Time) {
+	b.data = append(b.data, data...)
+	b.chunks = append(b.chunks, chunk{size: len(data), at: t, gap: t.Sub(b.last)})
+	b.last = t
+}
+
+// consume removes n bytes from the buffer and returns the timing of the removed bytes.
+func (b *framebuffer) consume(n int) (f Frame) {
+	if n > len(b.data) {
+		n = len(b.data)
+	}
+	b.data = b.data[n:]
+
+	for first := true; n > 0 && len(b.chunks) > 0; first = false {
+		c := &b.chunks[0]
+
+		if first {
+			f.Start = c.at
+			if !c.partial { // the line was idle before the chunk
+				f.Idle = c.gap
+			}
+		} else if c.gap > f.MaxCharDelay {
+			f.MaxCharDelay = c.gap
+		}
+		f.End = c.at
+
+		if n < c.size {
+			c.size -= n
+			c.partial = true
+			break
+		}
+
+		n -= c.size
+		b.chunks = b.chunks[1:]
+	}
+
+	return f
+}
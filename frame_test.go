@@ -0,0 +1,208 @@
+package framereader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+func TestFramebufferConsume(t *testing.T) {
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	buffer := framebuffer{last: t0}
+
+	buffer.append([]byte{1, 2}, t0.Add(100*time.Millisecond))
+	buffer.append([]byte{3}, t0.Add(105*time.Millisecond))
+	buffer.append([]byte{4, 5}, t0.Add(112*time.Millisecond))
+
+	f := buffer.consume(4)
+	if !f.Start.Equal(t0.Add(100*time.Millisecond)) || !f.End.Equal(t0.Add(112*time.Millisecond)) {
+		t.Error("unexpected start/end: ", f.Start, f.End)
+	}
+	if f.Idle != 100*time.Millisecond {
+		t.Error("expected idle to be 100ms: ", f.Idle)
+	}
+	if f.MaxCharDelay != 7*time.Millisecond {
+		t.Error("expected max char delay to be 7ms: ", f.MaxCharDelay)
+	}
+
+	f = buffer.consume(1)
+	if f.Idle != 0 || f.MaxCharDelay != 0 {
+		t.Error("expected no idle time within a chunk: ", f.Idle, f.MaxCharDelay)
+	}
+	if len(buffer.data) != 0 || len(buffer.chunks) != 0 {
+		t.Error("expected empty buffer: ", buffer.data, buffer.chunks)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	port := framereadertest.NewPort()
+	reader := NewReaderConfig(port, Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Clock: clock})
+	defer reader.Close()
+	defer port.Close()
+
+	clock.BlockUntilTimer(10 * time.Millisecond)
+	sendFrame(clock, port, 100*time.Millisecond, 0, 1)
+	clock.Advance(10 * time.Millisecond)
+
+	f, err := reader.ReadFrame()
+	if err != nil {
+		t.Error("read failed: ", err)
+	}
+
+	if len(f.Data) != 10 {
+		t.Error("expected 10 bytes: ", len(f.Data))
+	}
+	if f.Idle != 100*time.Millisecond {
+		t.Error("expected idle to be 100ms: ", f.Idle)
+	}
+	if f.MaxCharDelay != 5*time.Millisecond {
+		t.Error("expected max char delay to be 5ms: ", f.MaxCharDelay)
+	}
+	if !f.Start.Equal(epoch.Add(100*time.Millisecond)) || f.End.Sub(f.Start) != 45*time.Millisecond {
+		t.Error("expected frame from 100ms to 145ms: ", f.Start, f.End)
+	}
+	if f.Truncated {
+		t.Error("expected frame not to be truncated")
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	reader := NewReader(bytes.NewReader(make([]byte, 300)), time.Second, time.Millisecond*10)
+
+	f, err := reader.ReadFrame()
+	if err != nil {
+		t.Error("read failed: ", err)
+	}
+	if len(f.Data) != framesize || !f.Truncated {
+		t.Errorf("expected truncated frame of %v bytes, got %v bytes (truncated: %v)", framesize, len(f.Data), f.Truncated)
+	}
+
+	if _, err = reader.ReadFrame(); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	tests := []struct {
+		name      string
+		policy    OverflowPolicy
+		frames    [][]byte
+		err       error
+		truncated bool
+	}{
+		{"truncate", OverflowTruncate, [][]byte{data[:200]}, nil, true},
+		{"split", OverflowSplit, [][]byte{data[:200], data[200:]}, nil, false},
+		{"error", OverflowError, [][]byte{data[:200]}, ErrFrameTooLarge, true},
+	}
+
+	for _, tt := range tests {
+		reader := NewReaderConfig(bytes.NewReader(data), Config{
+			Timeout:         time.Second,
+			InterFrameDelay: 10 * time.Millisecond,
+			MaxFrameSize:    200,
+			Overflow:        tt.policy,
+		})
+
+		for _, exp := range tt.frames {
+			f, err := reader.ReadFrame()
+			if err != tt.err {
+				t.Errorf("%v: expected error %v, got %v", tt.name, tt.err, err)
+			}
+			if !bytes.Equal(f.Data, exp) {
+				t.Errorf("%v: expected %v bytes starting with %v, got %v bytes", tt.name, len(exp), exp[0], len(f.Data))
+			}
+			if f.Truncated != tt.truncated {
+				t.Errorf("%v: expected truncated %v", tt.name, tt.truncated)
+			}
+		}
+
+		if _, err := reader.ReadFrame(); err != io.EOF {
+			t.Errorf("%v: expected EOF, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestOverflowDelimiterFramer(t *testing.T) {
+	data := append(bytes.Repeat([]byte{'x'}, 20), "\nok\n"...)
+	reader := NewReaderConfig(bytes.NewReader(data), Config{
+		Timeout:         time.Second,
+		InterFrameDelay: 10 * time.Millisecond,
+		Framer:          NewDelimiterFramer('\n'),
+		MaxFrameSize:    8,
+	})
+
+	f, err := reader.ReadFrame()
+	if err != nil || !f.Truncated || string(f.Data) != "xxxxxxxx" {
+		t.Errorf("expected truncated frame, got %q (truncated: %v, err: %v)", f.Data, f.Truncated, err)
+	}
+
+	f, err = reader.ReadFrame()
+	if err != nil || string(f.Data) != "ok\n" {
+		t.Errorf("expected remainder of oversized frame to be dropped, got %q (err: %v)", f.Data, err)
+	}
+}
+
+func TestOverflowStreamed(t *testing.T) {
+	lengthPrefixed := []byte{0, 10, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 0, 2, 0xaa, 0xbb}
+	lines := []byte("xxxxxxxxxx\r\nok\r\n")
+	marked := []byte("\x02xxxxxxxx\x03\x02ok\x03")
+	hdlc := NewHDLCFramer(FCS16, ChecksumDrop)
+	encoded := append(hdlc.(Encoder).Encode([]byte("xxxxxxxx")), hdlc.(Encoder).Encode([]byte("ok"))...)
+
+	tests := []struct {
+		name      string
+		framer    Framer
+		policy    OverflowPolicy
+		data      []byte
+		frames    [][]byte
+		truncated bool
+		err       error
+	}{
+		{"length truncate", NewLengthPrefixFramer(2), OverflowTruncate, lengthPrefixed, [][]byte{{0, 10, 1, 2, 3, 4}, {0, 2, 0xaa, 0xbb}}, true, nil},
+		{"length error", NewLengthPrefixFramer(2), OverflowError, lengthPrefixed, [][]byte{{0, 10, 1, 2, 3, 4}, {0, 2, 0xaa, 0xbb}}, true, ErrFrameTooLarge},
+		{"length split", NewLengthPrefixFramer(2), OverflowSplit, lengthPrefixed, [][]byte{{0, 10, 1, 2, 3, 4}, {5, 6, 7, 8, 9, 10}, {0, 2, 0xaa, 0xbb}}, false, nil},
+		{"terminator truncate", &DelimiterFramer{End: []byte("\r\n")}, OverflowTruncate, lines, [][]byte{[]byte("xxxxxx"), []byte("ok\r\n")}, true, nil},
+		{"terminator split", &DelimiterFramer{End: []byte("\r\n")}, OverflowSplit, lines, [][]byte{[]byte("xxxxxx"), []byte("xxxx\r\n"), []byte("ok\r\n")}, false, nil},
+		{"start marker truncate", &DelimiterFramer{Start: []byte{2}, End: []byte{3}}, OverflowTruncate, marked, [][]byte{[]byte("\x02xxxxx"), []byte("\x02ok\x03")}, true, nil},
+		// the framer drops the remainder up to the next start marker, so the frame can't be split
+		{"start marker split", &DelimiterFramer{Start: []byte{2}, End: []byte{3}}, OverflowSplit, marked, [][]byte{[]byte("\x02xxxxx"), []byte("\x02ok\x03")}, true, nil},
+		{"start marker error", &DelimiterFramer{Start: []byte{2}, End: []byte{3}}, OverflowError, marked, [][]byte{[]byte("\x02xxxxx"), []byte("\x02ok\x03")}, true, ErrFrameTooLarge},
+		// the data of an encoded frame isn't delivered before it's decoded
+		{"encoded truncate", hdlc, OverflowTruncate, encoded, [][]byte{nil, []byte("ok")}, true, nil},
+		{"encoded split", hdlc, OverflowSplit, encoded, [][]byte{nil, []byte("ok")}, true, nil},
+		{"encoded error", hdlc, OverflowError, encoded, [][]byte{nil, []byte("ok")}, true, ErrFrameTooLarge},
+	}
+
+	for _, tt := range tests {
+		reader := NewReaderConfig(iotest.OneByteReader(bytes.NewReader(tt.data)), Config{
+			Timeout:         time.Second,
+			InterFrameDelay: 10 * time.Millisecond,
+			Framer:          tt.framer,
+			MaxFrameSize:    6,
+			Overflow:        tt.policy,
+		})
+
+		for i, exp := range tt.frames {
+			f, err := reader.ReadFrame()
+			if i == 0 && err != tt.err || i > 0 && err != nil {
+				t.Errorf("%v: unexpected error of frame %v: %v", tt.name, i, err)
+			}
+			if !bytes.Equal(f.Data, exp) || f.Truncated != (i == 0 && tt.truncated) {
+				t.Errorf("%v: expected frame %v %q (truncated: %v), got %q (truncated: %v)", tt.name, i, exp, i == 0 && tt.truncated, f.Data, f.Truncated)
+			}
+		}
+		if _, err := reader.ReadFrame(); err != io.EOF {
+			t.Errorf("%v: expected EOF, got %v", tt.name, err)
+		}
+	}
+}
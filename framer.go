@@ -0,0 +1,224 @@
+package framereader
+
+import (
+	"bytes"
+)
+
+// Framer splits the data received from the underlying io.Reader into frames.
+//
+// Split is called with the data received but not yet consumed by a frame.
+// idle reports that no more data has been received within the inter frame delay.
+// Split returns the number of bytes to consume from data and the frame found, if any.
+// If advance is 0 and frame is nil, the frame reader waits for more data.
+// An error is returned by Read together with the frame.
+//
+// The frame returned may be a sub slice of data, it is copied before it is passed to Read.
+type Framer interface {
+	Split(data []byte, idle bool) (advance int, frame []byte, err error)
+}
+
+// Resyncer is implemented by framers, which know how the remainder of a frame exceeding the max frame size is found.
+// If a framer doesn't implement Resyncer, the reader drops the data up to the end of the next frame returned by the framer,
+// which only works for frames ending with a terminator.
+type Resyncer interface {
+	// Resync returns the number of bytes following the first part head of an oversized frame,
+	// e.g. according to a length field in head. The reader drops these bytes or, with OverflowSplit,
+	// delivers them as frames. If Resync returns 0, the framer finds the start of the next frame by itself,
+	// e.g. framers searching for a start sequence, and the frame is truncated with every policy.
+	// If Resync returns a negative number, the remainder is unknown.
+	Resync(head []byte) int
+}
+
+// silenceFramer ends a frame, if no more data is received within the inter frame delay.
+type silenceFramer struct{}
+
+// NewSilenceFramer creates a framer which separates frames by the inter frame delay.
+// This is the default framer of Reader.
+func NewSilenceFramer() Framer {
+	return silenceFramer{}
+}
+
+// Split returns all buffered data, if the line is idle.
+func (silenceFramer) Split(data []byte, idle bool) (int, []byte, error) {
+	if !idle || len(data) == 0 {
+		return 0, nil, nil
+	}
+	return len(data), data, nil
+}
+
+// DelimiterFramer ends a frame with a terminator sequence, e.g. CR LF of line oriented devices
+// or STX and ETX of devices wrapping their messages:
+//
+//	&DelimiterFramer{Start: []byte{0x02}, End: []byte{0x03}}
+//
+// Start and End are part of the frame. Escape sequences are not removed from the frame.
+type DelimiterFramer struct {
+	// Start is the start marker of a frame. Data received before the start marker is dropped.
+	// If Start is empty, a frame starts with the first byte following the previous frame.
+	Start []byte
+
+	// End is the terminator sequence of a frame.
+	End []byte
+
+	// Escape is the escape sequence. The byte following the escape sequence doesn't end the frame.
+	// If Escape is empty, there is no escape sequence.
+	Escape []byte
+
+	// IgnoreIdle disables the inter frame delay as fallback, so a frame only ends with the terminator sequence.
+	// Otherwise a frame also ends, if the line is idle before the terminator sequence is received.
+	IgnoreIdle bool
+}
+
+// NewDelimiterFramer creates a framer which ends a frame with the delimiter byte.
+// The delimiter is part of the frame. If the line is idle before the delimiter is received, the frame ends anyway.
+func NewDelimiterFramer(delimiter byte) Framer {
+	return &DelimiterFramer{End: []byte{delimiter}}
+}
+
+// Split returns the data from the start marker up to and including the next terminator sequence.
+func (f DelimiterFramer) Split(data []byte, idle bool) (int, []byte, error) {
+	start := 0
+	if len(f.Start) > 0 {
+		i := bytes.Index(data, f.Start)
+		if i < 0 {
+			// drop the data, but keep a partial start marker
+			if n := len(data) - len(f.Start) + 1; n > 0 {
+				return n, nil, nil
+			}
+			return 0, nil, nil
+		}
+		if i > 0 {
+			return i, nil, nil
+		}
+		start = len(f.Start)
+	}
+
+	for i := start; i < len(data); {
+		switch {
+		case len(f.Escape) > 0 && bytes.HasPrefix(data[i:], f.Escape):
+			i += len(f.Escape) + 1
+		case len(f.End) > 0 && bytes.HasPrefix(data[i:], f.End):
+			n := i + len(f.End)
+			return n, data[:n], nil
+		default:
+			i++
+		}
+	}
+
+	if idle && !f.IgnoreIdle {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Resync returns 0, if the framer has a start marker, so data up to the next start marker is dropped by Split.
+// Otherwise the remainder of an oversized frame is unknown.
+func (f DelimiterFramer) Resync([]byte) int {
+	if len(f.Start) > 0 {
+		return 0
+	}
+	return -1
+}
+
+// LengthFramer reads frames with a header containing a length field, e.g. of binary protocols over TCP bridges,
+// where frames can't be separated by the inter frame delay, because they arrive coalesced.
+// The size of a frame is Offset + Size + length + Adjustment, the inter frame delay is ignored.
+type LengthFramer struct {
+	// Offset is the position of the length field in the header.
+	Offset int
+
+	// Size is the width of the length field, 1 to 4 bytes.
+	Size int
+
+	// LittleEndian is set, if the length field is little endian. Otherwise it's big endian.
+	LittleEndian bool
+
+	// Adjustment is added to the length field. It's used, if the length field doesn't count the bytes following it,
+	// e.g. -2, if the length includes the length field of 2 bytes, or the size of a trailer, which isn't included.
+	Adjustment int
+}
+
+// NewLengthPrefixFramer creates a framer for frames starting with a big endian length field of size 1, 2 or 4 bytes.
+// The length field counts the bytes following the length field. The length field is part of the frame.
+// The inter frame delay is ignored.
+func NewLengthPrefixFramer(size int) Framer {
+	switch size {
+	case 1, 2, 4:
+	default:
+		panic("framereader: length prefix size must be 1, 2 or 4")
+	}
+	return &LengthFramer{Size: size}
+}
+
+// Split returns the frame, if the header and all announced bytes are received.
+// If the length field announces a frame shorter than the header or the layout of the header is invalid,
+// all data is returned with ErrInvalidLength.
+func (f LengthFramer) Split(data []byte, _ bool) (int, []byte, error) {
+	if !f.valid() {
+		return len(data), data, ErrInvalidLength
+	}
+
+	header := f.Offset + f.Size
+	if len(data) < header {
+		return 0, nil, nil
+	}
+
+	n := f.size(data)
+	if n < header {
+		return len(data), data, ErrInvalidLength
+	}
+	if len(data) >= n {
+		return n, data[:n], nil
+	}
+	return 0, nil, nil
+}
+
+// Resync returns the number of bytes of the frame following head according to the length field.
+// It's negative, if head doesn't contain the length field.
+func (f LengthFramer) Resync(head []byte) int {
+	if !f.valid() || len(head) < f.Offset+f.Size {
+		return -1
+	}
+	return max(f.size(head)-len(head), 0)
+}
+
+// size returns the size of the frame announced by the length field of the header at the start of data.
+func (f LengthFramer) size(data []byte) int {
+	header := f.Offset + f.Size
+
+	var length int
+	for i := 0; i < f.Size; i++ {
+		b := data[f.Offset+i]
+		if f.LittleEndian {
+			b = data[header-1-i]
+		}
+		length = length<<8 | int(b)
+	}
+	return header + length + f.Adjustment
+}
+
+// valid reports whether the layout of the header is valid.
+func (f LengthFramer) valid() bool {
+	return f.Offset >= 0 && f.Size >= 1 && f.Size <= 4
+}
+
+// fixedSizeFramer reads frames of a fixed size.
+type fixedSizeFramer struct {
+	size int
+}
+
+// NewFixedSizeFramer creates a framer for frames of size bytes. The inter frame delay is ignored.
+func NewFixedSizeFramer(size int) Framer {
+	if size <= 0 {
+		panic("framereader: frame size must be positive")
+	}
+	return fixedSizeFramer{size: size}
+}
+
+// Split returns the next size bytes.
+func (f fixedSizeFramer) Split(data []byte, _ bool) (int, []byte, error) {
+	if len(data) < f.size {
+		return 0, nil, nil
+	}
+	return f.size, data[:f.size], nil
+}
@@ -0,0 +1,162 @@
+package framereader
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+func TestFramerSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		framer  Framer
+		data    []byte
+		idle    bool
+		advance int
+		frame   []byte
+	}{
+		{"silence busy", NewSilenceFramer(), []byte{1, 2, 3}, false, 0, nil},
+		{"silence idle", NewSilenceFramer(), []byte{1, 2, 3}, true, 3, []byte{1, 2, 3}},
+		{"delimiter incomplete", NewDelimiterFramer('\n'), []byte("ab"), false, 0, nil},
+		{"delimiter idle", NewDelimiterFramer('\n'), []byte("ab"), true, 2, []byte("ab")},
+		{"delimiter", NewDelimiterFramer('\n'), []byte("ab\ncd"), false, 3, []byte("ab\n")},
+		{"terminator", DelimiterFramer{End: []byte("\r\n")}, []byte("a\rb\r\nc"), false, 5, []byte("a\rb\r\n")},
+		{"terminator incomplete", DelimiterFramer{End: []byte("\r\n"), IgnoreIdle: true}, []byte("ab\r"), true, 0, nil},
+		{"terminator idle", DelimiterFramer{End: []byte("\r\n")}, []byte("ab\r"), true, 3, []byte("ab\r")},
+		{"terminator busy", DelimiterFramer{End: []byte("\r\n")}, []byte("ab\r"), false, 0, nil},
+		{"start marker", DelimiterFramer{Start: []byte{2}, End: []byte{3}}, []byte{2, 'a', 3, 2}, false, 3, []byte{2, 'a', 3}},
+		{"start marker garbage", DelimiterFramer{Start: []byte{2}, End: []byte{3}}, []byte{'x', 3, 2, 'a'}, false, 2, nil},
+		{"start marker missing", DelimiterFramer{Start: []byte("<<"), End: []byte{3}}, []byte("ab<"), false, 2, nil},
+		{"start marker partial", DelimiterFramer{Start: []byte("<<"), End: []byte{3}}, []byte("<"), true, 0, nil},
+		{"escape", DelimiterFramer{Start: []byte{2}, End: []byte{3}, Escape: []byte{0x10}}, []byte{2, 0x10, 3, 'a', 3}, false, 5, []byte{2, 0x10, 3, 'a', 3}},
+		{"escape incomplete", DelimiterFramer{End: []byte{3}, Escape: []byte{0x10}}, []byte{'a', 0x10, 3}, false, 0, nil},
+		{"length prefix 1", NewLengthPrefixFramer(1), []byte{2, 1, 2, 3}, false, 3, []byte{2, 1, 2}},
+		{"length prefix 2", NewLengthPrefixFramer(2), []byte{0, 1, 9}, false, 3, []byte{0, 1, 9}},
+		{"length prefix incomplete", NewLengthPrefixFramer(4), []byte{0, 0, 0, 2, 1}, false, 0, nil},
+		{"length offset", LengthFramer{Offset: 1, Size: 1}, []byte{9, 2, 1, 2, 3}, false, 4, []byte{9, 2, 1, 2}},
+		{"length little endian", LengthFramer{Size: 2, LittleEndian: true}, []byte{1, 0, 7, 8}, false, 3, []byte{1, 0, 7}},
+		{"length 3 bytes", LengthFramer{Size: 3}, []byte{0, 0, 1, 7, 8}, false, 4, []byte{0, 0, 1, 7}},
+		{"length adjustment", LengthFramer{Offset: 2, Size: 1, Adjustment: 2}, []byte{0x10, 0x20, 1, 9, 0xaa, 0xbb, 0}, false, 6, []byte{0x10, 0x20, 1, 9, 0xaa, 0xbb}},
+		{"length header incomplete", LengthFramer{Offset: 2, Size: 2}, []byte{1, 2, 3}, true, 0, nil},
+		{"fixed size", NewFixedSizeFramer(2), []byte{1, 2, 3}, false, 2, []byte{1, 2}},
+		{"fixed size incomplete", NewFixedSizeFramer(4), []byte{1, 2, 3}, true, 0, nil},
+	}
+
+	for _, tt := range tests {
+		advance, frame, err := tt.framer.Split(tt.data, tt.idle)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.name, err)
+		}
+		if advance != tt.advance {
+			t.Errorf("%v: expected advance %v, got %v", tt.name, tt.advance, advance)
+		}
+		if !reflect.DeepEqual(frame, tt.frame) {
+			t.Errorf("%v: expected frame %v, got %v", tt.name, tt.frame, frame)
+		}
+	}
+}
+
+func TestReaderDelimiterFramer(t *testing.T) {
+	pr, pw := io.Pipe()
+	reader := NewReaderConfig(pr, Config{
+		Timeout:         time.Second,
+		InterFrameDelay: 5 * time.Millisecond,
+		Framer:          &DelimiterFramer{End: []byte{'\n'}, IgnoreIdle: true},
+	})
+
+	go func() {
+		pw.Write([]byte("first\nsec"))
+		time.Sleep(20 * time.Millisecond)
+		pw.Write([]byte("ond\n"))
+	}()
+
+	for _, exp := range []string{"first\n", "second\n"} {
+		data := make([]byte, 100)
+		n, err := reader.Read(data)
+		if err != nil {
+			t.Error("read failed: ", err)
+		}
+		if got := string(data[:n]); got != exp {
+			t.Errorf("expected %q, got %q", exp, got)
+		}
+	}
+}
+
+func TestReaderDelimiterFramerIdle(t *testing.T) {
+	data := []byte("\x02first\x03garbage\x02second\x03\x02third")
+	reader := NewReaderConfig(bytes.NewReader(data), Config{
+		Timeout:         time.Second,
+		InterFrameDelay: 5 * time.Millisecond,
+		Framer:          DelimiterFramer{Start: []byte{0x02}, End: []byte{0x03}},
+	})
+
+	for _, exp := range []string{"\x02first\x03", "\x02second\x03", "\x02third"} {
+		f, err := reader.ReadFrame()
+		if err != nil || string(f.Data) != exp {
+			t.Errorf("expected %q, got %q (err: %v)", exp, f.Data, err)
+		}
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+}
+
+func TestLengthFramerInvalidLength(t *testing.T) {
+	framer := LengthFramer{Size: 2, Adjustment: -2}
+
+	advance, frame, err := framer.Split([]byte{0, 1, 5, 6}, false)
+	if advance != 4 || !bytes.Equal(frame, []byte{0, 1, 5, 6}) || err != ErrInvalidLength {
+		t.Errorf("expected invalid length, got %v, %v, %v", advance, frame, err)
+	}
+
+	if advance, frame, err = framer.Split([]byte{0, 4, 5, 6, 7}, false); advance != 4 || !bytes.Equal(frame, []byte{0, 4, 5, 6}) || err != nil {
+		t.Errorf("expected length including the length field, got %v, %v, %v", advance, frame, err)
+	}
+}
+
+func TestLengthFramerInvalidLayout(t *testing.T) {
+	for _, framer := range []LengthFramer{{Offset: 2}, {Size: 5}, {Offset: -1, Size: 1}} {
+		advance, frame, err := framer.Split([]byte{1, 2, 3}, false)
+		if advance != 3 || !bytes.Equal(frame, []byte{1, 2, 3}) || err != ErrInvalidLength {
+			t.Errorf("%+v: expected invalid length, got %v, %v, %v", framer, advance, frame, err)
+		}
+
+		// the reader doesn't panic, but returns the received data with the error
+		reader := NewReaderConfig(bytes.NewReader([]byte{1, 2, 3}), Config{Timeout: time.Second, Framer: &framer})
+		f, err := reader.ReadFrame()
+		if !bytes.Equal(f.Data, []byte{1, 2, 3}) || err != ErrInvalidLength {
+			t.Errorf("%+v: expected invalid length, got %v (err: %v)", framer, f.Data, err)
+		}
+		reader.Close()
+	}
+}
+
+func TestReaderLengthFramer(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	port := framereadertest.NewPort()
+	reader := NewReaderConfig(port, Config{
+		Timeout:         time.Second,
+		InterFrameDelay: 10 * time.Millisecond,
+		Framer:          &LengthFramer{Offset: 1, Size: 2, LittleEndian: true},
+		Clock:           clock,
+	})
+	defer reader.Close()
+	defer port.Close()
+
+	// coalesced frames and a frame split across chunks
+	clock.BlockUntilTimer(10 * time.Millisecond)
+	port.Send([]byte{0xa1, 1, 0, 'x', 0xa2, 2, 0, 'y'})
+	clock.BlockUntilTimer(10 * time.Millisecond)
+	port.Send([]byte{'z'})
+
+	for _, exp := range [][]byte{{0xa1, 1, 0, 'x'}, {0xa2, 2, 0, 'y', 'z'}} {
+		f, err := reader.ReadFrame()
+		if err != nil || !bytes.Equal(f.Data, exp) {
+			t.Errorf("expected %v, got %v (err: %v)", exp, f.Data, err)
+		}
+	}
+}
@@ -1,80 +1,243 @@
 package framereader
 
 import (
-	"encoding/hex"
+	"context"
 	"io"
-	"time"
 )
 
 func (r *Reader) framereader() {
+	defer r.wg.Done()
 	defer func() {
-		infolog.Println("stop frame reader services")
+		r.log.Info("stop frame reader services")
 		close(r.dataChan)
 	}()
 	data := make(chan []byte)
+	var readErr error
 
-	go func() { // this goroutine reads data from *Reader, until reader is closed reader.Closed
+	r.wg.Add(1)
+	go func() { // this goroutine reads data from *Reader, until the reader is closed
+		defer r.wg.Done()
 		defer func() {
-			infolog.Println("stop serialport reader")
+			r.log.Info("stop serialport reader")
 			close(data)
 		}()
-		for !r.closed {
+		for !r.isClosed() {
 			buffer := make([]byte, framesize)
-			if n, _ := r.reader.Read(buffer); n > 0 {
-				tracelog.Printf("read %v byte(s) from serial port: %v\n", n, hex.EncodeToString(buffer[:n]))
-				data <- buffer[:n]
+			n, err := r.reader.Read(buffer)
+			if n > 0 {
+				r.log.Log(context.Background(), LevelTrace, "read from serial port", "direction", "rx", "bytes", n, "data", hexdump(buffer[:n]))
+				select {
+				case data <- buffer[:n]:
+				case <-r.done:
+					return
+				}
+			}
+
+			if err != nil && !isTimeout(err) {
+				if !r.isClosed() {
+					// the error is passed to Read after all received frames
+					if err == io.EOF {
+						r.log.Info("end of data from serial port")
+					} else {
+						r.log.Warn("read from serial port failed", "direction", "rx", "error", err)
+					}
+					readErr = err
+				}
+				return
 			}
 		}
 	}()
 
-	for !r.closed { // this goroutine reads data from *Reader, until reader is closed reader.Closed
-		var icd, icdmax time.Duration
-		buffer := make([]byte, framesize)
+	buffer := framebuffer{last: r.clock.Now()}
 
-		n, err := func(frame []byte) (int, error) {
-			count := 0
-			for { // this goroutine reads a frame: appends data from serial port, until the delay between characters greater then the interframedelay
-				t := time.Now()
-				timeout := time.NewTimer(r.interframedelay)
-				select {
-				case chunk, ok := <-data:
-					if count > 0 {
-						icd = time.Since(t)
-					}
+	for { // collect chunks and pass them to the framer, until the reader is closed
+		timeout := newTimer(r.clock, r.interframedelay)
 
-					tracelog.Printf("read new chunk (icd): (%v) %v\n", icd, hex.EncodeToString(chunk[:]))
+		select {
+		case chunk, ok := <-data:
+			timeout.Stop()
 
-					if icd > icdmax {
-						// calc icdmax of the received Frame
-						icdmax = icd
-					}
+			if !ok { // the channel is closed, no more characters can received
+				r.log.Info("the channel is closed, no more characters can received, stop service")
+				if readErr != nil {
+					// deliver the remaining data, before the read error is returned
+					r.split(&buffer, true)
+					r.err = readErr
+				}
+				return
+			}
 
-					for i := 0; count < len(frame) && i < len(chunk); i++ {
-						frame[count] = chunk[i]
-						count++
-					}
+			t := r.clock.Now()
+			r.log.Log(context.Background(), LevelTrace, "read new chunk", "direction", "rx", "icd", t.Sub(buffer.last), "data", hexdump(chunk))
+			buffer.append(chunk, t)
+			r.split(&buffer, false)
 
-					if !ok { // the channel is closed, no more characters can received
-						tracelog.Println("the channel is closed, no more characters can received, exit with EOF")
-						return count, io.EOF
-					}
+		case <-timeout.C:
+			if len(buffer.data) > 0 {
+				r.split(&buffer, true)
+			}
 
-				case <-timeout.C:
-					icd = time.Since(t)
-					return count, nil
-				}
+		case <-r.reset:
+			timeout.Stop()
+			if len(buffer.data) > 0 || buffer.remainder > 0 || buffer.discard {
+				r.log.Log(context.Background(), LevelTrace, "drop buffered data", "direction", "rx", "data", hexdump(buffer.data))
 			}
-		}(buffer)
+			buffer = framebuffer{last: buffer.last}
 
-		if err != nil {
-			infolog.Println("the channel is closed, no more characters can received, stop service")
+		case <-r.done:
+			timeout.Stop()
+			return
+		}
+	}
+}
+
+// split passes the buffered data to the framer and delivers all frames found.
+// The data consumed by the framer is removed from the buffer.
+func (r *Reader) split(buffer *framebuffer, idle bool) {
+	for {
+		r.remainder(buffer)
+		if buffer.remainder > 0 {
+			// wait for the rest of an oversized frame
 			return
 		}
 
-		if n > 0 {
-			// New Frame received
-			debuglog.Printf("read new frame (ifd/icdmax): (%v/%v) %v\n", icd, icdmax, hex.EncodeToString(buffer[:n]))
-			r.dataChan <- buffer[:n]
+		r.frames(buffer, idle)
+		if len(buffer.data) <= r.maxframesize || !r.oversized(buffer) {
+			return
 		}
 	}
 }
+
+// frames passes the buffered data to the framer and delivers all frames found.
+func (r *Reader) frames(buffer *framebuffer, idle bool) {
+	for len(buffer.data) > 0 {
+		advance, data, err := r.framer.Split(buffer.data, idle)
+		if advance < 0 {
+			advance = 0
+		}
+
+		var f frame
+		if data != nil || err != nil {
+			// copy the frame, before the buffer is modified by consume
+			f = frame{Frame: Frame{Data: append([]byte(nil), data...)}, err: err}
+		}
+		timing := buffer.consume(advance)
+
+		if f.Data != nil || f.err != nil {
+			f.Start, f.End, f.MaxCharDelay, f.Idle = timing.Start, timing.End, timing.MaxCharDelay, timing.Idle
+
+			if buffer.discard {
+				// the frame is the remainder of an oversized frame
+				r.log.Log(context.Background(), LevelTrace, "drop remainder of oversized frame", "direction", "rx", "data", hexdump(f.Data))
+				buffer.discard = false
+			} else {
+				r.deliver(f)
+			}
+		}
+
+		if advance == 0 {
+			break
+		}
+	}
+}
+
+// oversized handles the buffered data, if the framer didn't find the end of the frame within the max frame size.
+// The first part of the frame is delivered according to the overflow policy and the framer is asked by Resync,
+// how much of the frame follows. If the size of the remainder is unknown, the remainder is dropped up to
+// the end of the next frame returned by the framer. If the framer finds the next frame by itself or
+// the frame is encoded, the remainder can't be delivered, so the frame is truncated with every policy.
+// The data of an encoded frame isn't delivered, because it can't be decoded without the remainder.
+// oversized reports whether the first part of a frame has been delivered.
+func (r *Reader) oversized(buffer *framebuffer) bool {
+	if buffer.discard {
+		// keep the tail of the buffer, so the framer is still able to detect the end of the oversized frame
+		buffer.consume(len(buffer.data) - r.maxframesize)
+		return false
+	}
+
+	data := append([]byte(nil), buffer.data[:r.maxframesize]...)
+	f := frame{Frame: buffer.consume(r.maxframesize)}
+	f.Data = data
+
+	remainder := -1
+	if resyncer, ok := r.framer.(Resyncer); ok {
+		remainder = resyncer.Resync(data)
+	}
+	_, encoded := r.framer.(Encoder)
+
+	if r.overflow == OverflowSplit && remainder != 0 && !encoded {
+		r.log.Debug("split frame at max frame size", "direction", "rx", "maxframesize", r.maxframesize)
+		buffer.remainder = max(remainder, 0)
+		r.send(f)
+		return true
+	}
+
+	if r.overflow == OverflowError {
+		f.err = ErrFrameTooLarge
+	}
+	if encoded {
+		f.Data = nil
+	}
+	r.log.Warn("frame truncated to max frame size", "direction", "rx", "maxframesize", r.maxframesize)
+	f.Truncated = true
+	buffer.remainder = max(remainder, 0)
+	buffer.discard = remainder < 0
+	r.send(f)
+	return true
+}
+
+// remainder handles the remainder of an oversized frame of known size:
+// it's delivered in frames of max frame size with OverflowSplit and dropped otherwise.
+func (r *Reader) remainder(buffer *framebuffer) {
+	for buffer.remainder > 0 && len(buffer.data) > 0 {
+		n := min(buffer.remainder, len(buffer.data), r.maxframesize)
+
+		if r.overflow == OverflowSplit {
+			if n < min(buffer.remainder, r.maxframesize) {
+				// wait for the rest of the part
+				return
+			}
+			data := append([]byte(nil), buffer.data[:n]...)
+			f := frame{Frame: buffer.consume(n)}
+			f.Data = data
+			r.send(f)
+		} else {
+			r.log.Log(context.Background(), LevelTrace, "drop remainder of oversized frame", "direction", "rx", "data", hexdump(buffer.data[:n]))
+			buffer.consume(n)
+		}
+		buffer.remainder -= n
+	}
+}
+
+// deliver passes the frame to Read. Frames exceeding the max frame size are handled according to the overflow policy.
+func (r *Reader) deliver(f frame) {
+	for len(f.Data) > r.maxframesize {
+		switch r.overflow {
+		case OverflowSplit:
+			r.log.Debug("split frame at max frame size", "direction", "rx", "bytes", len(f.Data), "maxframesize", r.maxframesize)
+			part := f
+			part.Data = f.Data[:r.maxframesize]
+			r.send(part)
+			f.Data = f.Data[r.maxframesize:]
+			continue
+		case OverflowError:
+			f.err = ErrFrameTooLarge
+		}
+
+		r.log.Warn("frame truncated to max frame size", "direction", "rx", "bytes", len(f.Data), "maxframesize", r.maxframesize)
+		f.Data = f.Data[:r.maxframesize]
+		f.Truncated = true
+	}
+
+	r.send(f)
+}
+
+// send passes the frame to Read.
+func (r *Reader) send(f frame) {
+	// New Frame received
+	r.log.Debug("read new frame", "direction", "rx", "ifd", f.Idle, "icdmax", f.MaxCharDelay, "data", hexdump(f.Data))
+	select {
+	case r.dataChan <- f:
+	case <-r.done:
+	}
+}
@@ -0,0 +1,116 @@
+// Package framereadertest provides a fake clock and a fake port to test code using package framereader
+// without depending on the real time. A fake transport for the protocol packages is provided by package transporttest.
+package framereadertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock implementing framereader.Clock. The time only moves on, if Advance is called.
+type Clock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*clockTimer
+	armed  []time.Duration // durations of the AfterFunc calls not yet waited for by BlockUntilTimer
+}
+
+// clockTimer is a pending AfterFunc call of Clock.
+type clockTimer struct {
+	at time.Time
+	f  func()
+}
+
+// NewClock creates a fake clock set to now.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of the fake clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc calls f, when the fake clock is advanced by d or more.
+// f is called by the goroutine calling Advance.
+func (c *Clock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &clockTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	c.armed = append(c.armed, d)
+	c.cond.Broadcast()
+
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.remove(t)
+	}
+}
+
+// Advance moves the fake clock forward by d and calls all functions, which are due, in the order of their due time.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+
+	var due []*clockTimer
+	for _, t := range c.timers {
+		if !t.at.After(now) {
+			due = append(due, t)
+		}
+	}
+	for _, t := range due {
+		c.remove(t)
+	}
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// Timers returns the number of pending timers.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntilTimer blocks, until AfterFunc has been called with d.
+// Each AfterFunc call is reported once, so the calls of BlockUntilTimer have to match the calls of AfterFunc.
+// It's used to wait, until a goroutine has armed its timer, before the clock is advanced.
+func (c *Clock) BlockUntilTimer(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		for i, armed := range c.armed {
+			if armed == d {
+				c.armed = append(c.armed[:i], c.armed[i+1:]...)
+				return
+			}
+		}
+		c.cond.Wait()
+	}
+}
+
+// remove removes t from the pending timers. It returns false, if t isn't pending. c.mu must be held.
+func (c *Clock) remove(t *clockTimer) bool {
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
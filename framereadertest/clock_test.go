@@ -0,0 +1,42 @@
+package framereadertest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	var fired []int
+	clock.AfterFunc(20*time.Millisecond, func() { fired = append(fired, 2) })
+	clock.AfterFunc(10*time.Millisecond, func() { fired = append(fired, 1) })
+	stop := clock.AfterFunc(15*time.Millisecond, func() { fired = append(fired, 3) })
+
+	clock.BlockUntilTimer(15 * time.Millisecond)
+	if !stop() {
+		t.Error("expected pending timer to be stopped")
+	}
+	if stop() {
+		t.Error("expected stopped timer not to be stopped again")
+	}
+
+	clock.Advance(15 * time.Millisecond)
+	if !reflect.DeepEqual(fired, []int{1}) {
+		t.Error("expected first timer to be fired: ", fired)
+	}
+
+	clock.Advance(10 * time.Millisecond)
+	if !reflect.DeepEqual(fired, []int{1, 2}) {
+		t.Error("expected second timer to be fired: ", fired)
+	}
+
+	if now := clock.Now(); !now.Equal(start.Add(25 * time.Millisecond)) {
+		t.Error("expected clock to be advanced by 25ms: ", now)
+	}
+	if n := clock.Timers(); n != 0 {
+		t.Error("expected no pending timers: ", n)
+	}
+}
@@ -0,0 +1,70 @@
+package framereadertest
+
+import (
+	"io"
+	"sync"
+)
+
+// Port is a fake serial port. Read returns the chunks passed to Send, Write records the written data.
+type Port struct {
+	chunks  chan []byte
+	closed  chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	written [][]byte
+}
+
+// NewPort creates a fake port.
+func NewPort() *Port {
+	return &Port{
+		chunks: make(chan []byte),
+		closed: make(chan struct{}),
+	}
+}
+
+// Send passes chunk to the next Read call. It blocks, until chunk is read or the port is closed.
+func (p *Port) Send(chunk []byte) {
+	select {
+	case p.chunks <- chunk:
+	case <-p.closed:
+	}
+}
+
+// Read blocks, until a chunk is sent or the port is closed. A closed port returns io.EOF.
+func (p *Port) Read(data []byte) (int, error) {
+	select {
+	case chunk := <-p.chunks:
+		return copy(data, chunk), nil
+	case <-p.closed:
+		return 0, io.EOF
+	}
+}
+
+// Write records data. It returns io.ErrClosedPipe, if the port is closed.
+func (p *Port) Write(data []byte) (int, error) {
+	select {
+	case <-p.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written = append(p.written, append([]byte(nil), data...))
+	return len(data), nil
+}
+
+// Written returns the data of all Write calls.
+func (p *Port) Written() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]byte(nil), p.written...)
+}
+
+// Close unblocks pending Read and Send calls.
+func (p *Port) Close() error {
+	p.once.Do(func() {
+		close(p.closed)
+	})
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package transporttest provides a fake transport to test the protocol packages built on package framereader
+// without a port. It's not part of package framereadertest, because it depends on package framereader,
+// whose tests depend on package framereadertest.
+package transporttest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/womat/framereader"
+)
+
+// Transport is a fake transport answering each request with the frames returned by Respond.
+// It implements framereader.Transactor as well as WriteContext and ReadFrameContext of framereader.ReadWriteCloser.
+type Transport struct {
+	// Respond returns the frames received after request is written. If Respond is nil, no frames are received.
+	Respond func(request []byte) [][]byte
+
+	// Echo is set, if each request is received before the response, e.g. by an optical probe.
+	Echo bool
+
+	mu      sync.Mutex
+	written [][]byte
+	frames  [][]byte // received frames, which are not read yet
+}
+
+// WriteContext records request. The frames received before and not read yet are dropped.
+func (t *Transport) WriteContext(_ context.Context, request []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	request = append([]byte(nil), request...)
+	t.written = append(t.written, request)
+	t.frames = nil
+	if t.Echo {
+		t.frames = append(t.frames, request)
+	}
+	if t.Respond != nil {
+		t.frames = append(t.frames, t.Respond(request)...)
+	}
+	return len(request), nil
+}
+
+// ReadFrameContext returns the next received frame. If there is none, framereader.ErrTimeout is returned.
+func (t *Transport) ReadFrameContext(context.Context) (framereader.Frame, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.frames) == 0 {
+		return framereader.Frame{}, framereader.ErrTimeout
+	}
+	f := framereader.Frame{Data: t.frames[0]}
+	t.frames = t.frames[1:]
+	return f, nil
+}
+
+// Transact writes request and returns the first received frame satisfying match, see framereader.Transactor.
+// If no frame satisfies match, framereader.ErrTimeout is returned.
+func (t *Transport) Transact(ctx context.Context, request []byte, match func(framereader.Frame) bool) (framereader.Frame, error) {
+	t.WriteContext(ctx, request)
+	if match == nil {
+		return framereader.Frame{}, nil
+	}
+
+	for {
+		f, err := t.ReadFrameContext(ctx)
+		if err != nil || match(f) {
+			return f, err
+		}
+	}
+}
+
+// Written returns the requests written.
+func (t *Transport) Written() [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([][]byte(nil), t.written...)
+}
@@ -1,6 +1,6 @@
 module github.com/womat/framereader
 
-go 1.15
+go 1.21
 
 require (
 )
@@ -0,0 +1,198 @@
+package framereader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/womat/framereader/internal/crc"
+)
+
+// Special characters of HDLC.
+const (
+	hdlcFlag   = 0x7e
+	hdlcEscape = 0x7d
+	hdlcXor    = 0x20 // escaped bytes are xored with hdlcXor
+)
+
+// FCS is the frame check sequence of HDLC.
+type FCS int
+
+const (
+	// FCS16 is the 16 bit frame check sequence (CRC-16/X-25).
+	FCS16 FCS = 2
+
+	// FCS32 is the 32 bit frame check sequence (CRC-32 of IEEE 802.3).
+	FCS32 FCS = 4
+)
+
+// sum returns the frame check sequence of data.
+func (f FCS) sum(data []byte) []byte {
+	if f == FCS32 {
+		return binary.LittleEndian.AppendUint32(nil, crc32.ChecksumIEEE(data))
+	}
+	return binary.LittleEndian.AppendUint16(nil, crc.X25(data))
+}
+
+// hdlcFramer decodes and encodes HDLC frames.
+type hdlcFramer struct {
+	fcs    FCS
+	policy ChecksumPolicy
+}
+
+// NewHDLCFramer creates a framer for HDLC-like frames with byte stuffing as used by PPP.
+// Frames are enclosed by flags (0x7e), flags and escapes (0x7d) in the frame are escaped.
+// DLMS/COSEM doesn't escape the frames, NewDLMSFramer has to be used instead.
+// The frames are decoded on Read and the frame check sequence is validated and removed.
+// On Write, the frame check sequence is added and the frame is encoded. The inter frame delay is ignored.
+// With ChecksumError, frames with an invalid frame check sequence are returned including the frame check sequence.
+func NewHDLCFramer(fcs FCS, policy ChecksumPolicy) Framer {
+	return hdlcFramer{fcs: fcs, policy: policy}
+}
+
+// Resync returns 0, because the data up to the next flag is dropped by Split.
+func (hdlcFramer) Resync([]byte) int {
+	return 0
+}
+
+// Split returns the decoded data between two flags. Data received before the first flag is dropped.
+// The closing flag is kept as opening flag of the next frame, so frames may be sent back to back with a shared flag.
+func (f hdlcFramer) Split(data []byte, _ bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, hdlcFlag); i != 0 {
+		if i < 0 {
+			i = len(data)
+		}
+		return i, nil, nil
+	}
+
+	end := bytes.IndexByte(data[1:], hdlcFlag) + 1
+	if end == 0 {
+		return 0, nil, nil
+	}
+	if end == 1 {
+		// empty frame between two flags
+		return 1, nil, nil
+	}
+
+	frame := make([]byte, 0, end-1)
+	for j := 1; j < end; j++ {
+		b := data[j]
+		if b == hdlcEscape && j+1 < end {
+			j++
+			b = data[j] ^ hdlcXor
+		}
+		frame = append(frame, b)
+	}
+
+	n := len(frame) - int(f.fcs)
+	if n >= 0 && bytes.Equal(frame[n:], f.fcs.sum(frame[:n])) {
+		return end, frame[:n], nil
+	}
+	if f.policy == ChecksumError {
+		return end, frame, ErrChecksum
+	}
+	return end, nil, nil
+}
+
+// Encode returns the HDLC frame of data including the frame check sequence and the flags.
+func (f hdlcFramer) Encode(data []byte) []byte {
+	frame := make([]byte, 0, len(data)+2*int(f.fcs)+2)
+	frame = append(frame, hdlcFlag)
+	for _, b := range append(data[:len(data):len(data)], f.fcs.sum(data)...) {
+		if b == hdlcFlag || b == hdlcEscape {
+			frame = append(frame, hdlcEscape, b^hdlcXor)
+		} else {
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, hdlcFlag)
+}
+
+// dlmsFramer decodes and encodes the HDLC frames of DLMS/COSEM.
+type dlmsFramer struct {
+	policy ChecksumPolicy
+}
+
+// dlmsFormat is the frame format type 3 in the high nibble of the frame format field.
+const dlmsFormat = 0xa0
+
+// NewDLMSFramer creates a framer for the HDLC frames of DLMS/COSEM (IEC 62056-46, frame format type 3).
+// The frames are enclosed by flags (0x7e), but not escaped: the end of a frame is given by the length
+// in the frame format field, so flags within a frame are data.
+// The frames returned start with the frame format field, the FCS16 is validated and removed.
+// On Write, the frame has to start with the frame format field including the length and contain the HCS,
+// the FCS16 and the flags are added. The inter frame delay is ignored.
+// The max frame size of the reader has to be configured according to the negotiated max information field length.
+// With ChecksumError, frames with an invalid frame check sequence are returned including the frame check sequence.
+func NewDLMSFramer(policy ChecksumPolicy) Framer {
+	return dlmsFramer{policy: policy}
+}
+
+// Split returns the frame following a flag, if all bytes announced by the frame format field are received.
+// Data not starting with a flag and a valid frame format field is dropped.
+// The closing flag is kept as opening flag of the next frame.
+func (f dlmsFramer) Split(data []byte, _ bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, hdlcFlag); i != 0 {
+		if i < 0 {
+			i = len(data)
+		}
+		return i, nil, nil
+	}
+	if len(data) < 3 {
+		return 0, nil, nil
+	}
+
+	length, ok := dlmsLength(data[1:])
+	if !ok {
+		// no frame format field, e.g. a closing flag followed by an opening flag
+		return 1, nil, nil
+	}
+	end := 1 + length
+	if len(data) <= end {
+		return 0, nil, nil
+	}
+	if data[end] != hdlcFlag {
+		return 1, nil, nil
+	}
+
+	frame := data[1:end]
+	n := len(frame) - int(FCS16)
+	if bytes.Equal(frame[n:], FCS16.sum(frame[:n])) {
+		return end, frame[:n], nil
+	}
+	if f.policy == ChecksumError {
+		return end, frame, ErrChecksum
+	}
+	return end, nil, nil
+}
+
+// Resync returns the number of bytes of the frame following head according to the frame format field.
+func (dlmsFramer) Resync(head []byte) int {
+	if len(head) < 3 {
+		return 0
+	}
+	length, ok := dlmsLength(head[1:])
+	if !ok {
+		return 0
+	}
+	return max(1+length-len(head), 0)
+}
+
+// Encode returns the frame including the FCS16 and the flags.
+func (dlmsFramer) Encode(frame []byte) []byte {
+	encoded := make([]byte, 0, len(frame)+int(FCS16)+2)
+	encoded = append(encoded, hdlcFlag)
+	encoded = append(encoded, frame...)
+	encoded = append(encoded, FCS16.sum(frame)...)
+	return append(encoded, hdlcFlag)
+}
+
+// dlmsLength returns the frame length of the frame format field at the start of data.
+// ok is false, if data doesn't start with a frame format field of type 3 announcing at least the FCS.
+func dlmsLength(data []byte) (length int, ok bool) {
+	if data[0]&0xf0 != dlmsFormat {
+		return 0, false
+	}
+	length = int(data[0]&0x07)<<8 | int(data[1])
+	return length, length >= 2+int(FCS16)
+}
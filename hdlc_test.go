@@ -0,0 +1,129 @@
+package framereader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestHDLCFramer(t *testing.T) {
+	// SNRM frame of DLMS/COSEM
+	snrm := []byte{0x7e, 0xa0, 0x07, 0x03, 0x21, 0x93, 0x0f, 0x01, 0x7e}
+	framer := NewHDLCFramer(FCS16, ChecksumDrop)
+
+	advance, frame, err := framer.Split(snrm, false)
+	if advance != 8 || !bytes.Equal(frame, []byte{0xa0, 0x07, 0x03, 0x21, 0x93}) || err != nil {
+		t.Errorf("unexpected decoding %v, % x, %v", advance, frame, err)
+	}
+	if encoded := framer.(Encoder).Encode(frame); !bytes.Equal(encoded, snrm) {
+		t.Errorf("expected encoding % x, got % x", snrm, encoded)
+	}
+
+	for _, fcs := range []FCS{FCS16, FCS32} {
+		framer = NewHDLCFramer(fcs, ChecksumError)
+		data := []byte{1, 0x7e, 2, 0x7d, 3}
+		encoded := framer.(Encoder).Encode(data)
+		if bytes.Count(encoded, []byte{0x7e}) != 2 || len(encoded) < len(data)+int(fcs)+4 {
+			t.Errorf("FCS%v: expected flag and escape to be escaped: % x", 8*fcs, encoded)
+		}
+
+		if advance, frame, err = framer.Split(encoded, false); advance != len(encoded)-1 || !bytes.Equal(frame, data) || err != nil {
+			t.Errorf("FCS%v: unexpected decoding %v, % x, %v", 8*fcs, advance, frame, err)
+		}
+
+		encoded[1]++
+		if _, frame, err = framer.Split(encoded, false); len(frame) != len(data)+int(fcs) || err != ErrChecksum {
+			t.Errorf("FCS%v: expected checksum error, got % x, %v", 8*fcs, frame, err)
+		}
+	}
+}
+
+func TestReaderHDLCFramer(t *testing.T) {
+	framer := NewHDLCFramer(FCS16, ChecksumDrop)
+	encode := framer.(Encoder).Encode
+
+	var data []byte
+	data = append(data, 0x01, 0x02) // end of a frame received partially
+	data = append(data, encode([]byte("first"))...)
+	data = append(data, encode([]byte("second"))[1:]...) // back to back with a shared flag
+	invalid := encode([]byte("invalid"))
+	invalid[3]++
+	data = append(data, invalid...)
+	data = append(data, encode([]byte("third"))...)
+
+	reader := NewReaderConfig(iotest.OneByteReader(bytes.NewReader(data)), Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Framer: framer})
+
+	for _, exp := range []string{"first", "second", "third"} {
+		f, err := reader.ReadFrame()
+		if err != nil || string(f.Data) != exp {
+			t.Errorf("expected %q, got %q (err: %v)", exp, f.Data, err)
+		}
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+}
+
+func TestDLMSFramer(t *testing.T) {
+	snrm := []byte{0x7e, 0xa0, 0x07, 0x03, 0x21, 0x93, 0x0f, 0x01, 0x7e}
+	framer := NewDLMSFramer(ChecksumError)
+
+	advance, frame, err := framer.Split(snrm, false)
+	if advance != 8 || !bytes.Equal(frame, []byte{0xa0, 0x07, 0x03, 0x21, 0x93}) || err != nil {
+		t.Errorf("unexpected decoding %v, % x, %v", advance, frame, err)
+	}
+	if encoded := framer.(Encoder).Encode(frame); !bytes.Equal(encoded, snrm) {
+		t.Errorf("expected encoding % x, got % x", snrm, encoded)
+	}
+
+	// flags and escapes within the frame aren't escaped
+	data := []byte{0xa0, 0x0a, 0x03, 0x21, 0x10, 0x7e, 0x7d, 0x7e}
+	encoded := framer.(Encoder).Encode(data)
+	if len(encoded) != len(data)+4 {
+		t.Errorf("expected frame not to be escaped: % x", encoded)
+	}
+	if advance, frame, err = framer.Split(encoded, false); advance != len(encoded)-1 || !bytes.Equal(frame, data) || err != nil {
+		t.Errorf("unexpected decoding %v, % x, %v", advance, frame, err)
+	}
+	if advance, frame, err = framer.Split(encoded[:len(encoded)-1], false); advance != 0 || frame != nil || err != nil {
+		t.Errorf("expected framer to wait for the closing flag, got %v, % x, %v", advance, frame, err)
+	}
+
+	encoded[4]++
+	if _, frame, err = framer.Split(encoded, false); len(frame) != len(data)+2 || err != ErrChecksum {
+		t.Errorf("expected checksum error, got % x, %v", frame, err)
+	}
+}
+
+func TestReaderDLMSFramer(t *testing.T) {
+	framer := NewDLMSFramer(ChecksumDrop)
+	encode := func(info ...byte) []byte {
+		frame := append([]byte{0xa0, byte(len(info) + 7), 0x03, 0x21, 0x10}, info...)
+		return framer.(Encoder).Encode(frame)
+	}
+
+	first, second, third := encode(0x7e, 0x7e), encode(0x7d, 0x01), encode(0x7e, 0xa0, 0x07)
+	invalid := encode(0x01)
+	invalid[6]++
+
+	var data []byte
+	data = append(data, 0x01, 0x7e) // end of a frame received partially
+	data = append(data, first...)
+	data = append(data, second[1:]...) // back to back with a shared flag
+	data = append(data, invalid...)
+	data = append(data, third...)
+
+	reader := NewReaderConfig(iotest.OneByteReader(bytes.NewReader(data)), Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Framer: framer})
+
+	for _, exp := range [][]byte{first, second, third} {
+		f, err := reader.ReadFrame()
+		if exp := exp[1 : len(exp)-3]; err != nil || !bytes.Equal(f.Data, exp) {
+			t.Errorf("expected % x, got % x (err: %v)", exp, f.Data, err)
+		}
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+}
@@ -0,0 +1,217 @@
+package framereader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxFailures is the number of consecutive failures used by NewHealth, after which a device is offline.
+	DefaultMaxFailures = 3
+
+	// DefaultProbeInterval is the interval used by NewHealth, in which offline devices are probed.
+	DefaultProbeInterval = time.Minute
+)
+
+// DeviceHealth is the state of a device tracked by Health.
+type DeviceHealth struct {
+	// Address identifies the device, e.g. the Modbus slave address.
+	Address int
+
+	// Online is false, after the device failed the max number of consecutive transactions.
+	// It's true again, after the next successful transaction.
+	Online bool
+
+	// Successes, Timeouts and Errors are the numbers of successful transactions,
+	// transactions failed by ErrTimeout and transactions failed by other errors.
+	Successes, Timeouts, Errors int
+
+	// Failures is the number of consecutive failed transactions.
+	Failures int
+
+	// LastSuccess and LastFailure are the end times of the last successful and the last failed transaction.
+	LastSuccess, LastFailure time.Time
+
+	// LastError is the error of the last failed transaction.
+	LastError error
+}
+
+// Health tracks the results of the transactions per device and detects offline devices,
+// so dead devices can be told apart from a slow line.
+//
+// A device is offline after a number of consecutive failed transactions and online again after
+// a successful transaction. A Scheduler using Health polls offline devices at the slower probe interval only.
+type Health struct {
+	maxFailures   int
+	probeInterval time.Duration
+	clock         Clock
+	onChange      func(DeviceHealth)
+
+	mu      sync.Mutex             // guards the following field
+	devices map[int]*trackedDevice // by address
+}
+
+// trackedDevice is the state of a device of Health.
+type trackedDevice struct {
+	DeviceHealth
+	probe time.Time // next probe of an offline device
+}
+
+// HealthConfig is used to configure Health.
+type HealthConfig struct {
+	// MaxFailures is the number of consecutive failed transactions, after which a device is offline.
+	// If MaxFailures is 0, DefaultMaxFailures is used.
+	MaxFailures int
+
+	// ProbeInterval is the interval, in which offline devices are probed.
+	// If ProbeInterval is 0, DefaultProbeInterval is used.
+	ProbeInterval time.Duration
+
+	// OnChange receives the state of a device, whenever it goes offline or online again,
+	// e.g. to update a dashboard. It's called by the goroutine recording the result of the transaction.
+	OnChange func(DeviceHealth)
+
+	// Clock is the source of time for the timestamps and the probes. If Clock is nil, the real time is used.
+	Clock Clock
+}
+
+// NewHealth creates a health tracker using DefaultMaxFailures and DefaultProbeInterval.
+func NewHealth() *Health {
+	return NewHealthConfig(HealthConfig{})
+}
+
+// NewHealthConfig creates a health tracker using config.
+func NewHealthConfig(config HealthConfig) *Health {
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = DefaultMaxFailures
+	}
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = DefaultProbeInterval
+	}
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+	return &Health{
+		maxFailures:   config.MaxFailures,
+		probeInterval: config.ProbeInterval,
+		clock:         config.Clock,
+		onChange:      config.OnChange,
+		devices:       map[int]*trackedDevice{},
+	}
+}
+
+// Record records the result of a transaction with the device at address. err is nil for a successful transaction.
+func (h *Health) Record(address int, err error) {
+	h.mu.Lock()
+	d := h.device(address)
+	now := h.clock.Now()
+	online := d.Online
+
+	if err == nil {
+		d.Successes++
+		d.Failures = 0
+		d.LastSuccess = now
+		d.Online = true
+	} else {
+		if errors.Is(err, ErrTimeout) {
+			d.Timeouts++
+		} else {
+			d.Errors++
+		}
+		d.Failures++
+		d.LastFailure = now
+		d.LastError = err
+		if d.Failures >= h.maxFailures {
+			d.Online = false
+			d.probe = now.Add(h.probeInterval)
+		}
+	}
+
+	state := d.DeviceHealth
+	h.mu.Unlock()
+
+	if state.Online != online && h.onChange != nil {
+		h.onChange(state)
+	}
+}
+
+// Device returns the state of the device at address. Devices without recorded transactions are online.
+func (h *Health) Device(address int) DeviceHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if d, ok := h.devices[address]; ok {
+		return d.DeviceHealth
+	}
+	return DeviceHealth{Address: address, Online: true}
+}
+
+// Devices returns the state of all devices with recorded transactions ordered by address.
+func (h *Health) Devices() []DeviceHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	devices := make([]DeviceHealth, 0, len(h.devices))
+	for _, d := range h.devices {
+		devices = append(devices, d.DeviceHealth)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Address < devices[j].Address })
+	return devices
+}
+
+// Online reports whether the device at address is online.
+func (h *Health) Online(address int) bool {
+	return h.Device(address).Online
+}
+
+// Track returns a transactor, which records the result of each transaction of transactor.
+// address returns the address of the device a request is sent to, e.g. the slave address of a Modbus request.
+// Transactions without response, i.e. with a nil matcher, and transactions canceled by ctx aren't recorded.
+func (h *Health) Track(transactor Transactor, address func(request []byte) int) Transactor {
+	return &trackingTransactor{transactor: transactor, health: h, address: address}
+}
+
+// probe reports whether a transaction with the device at address may start.
+// It's true for online devices and, once per probe interval, for offline devices.
+func (h *Health) probe(address int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	d, ok := h.devices[address]
+	if !ok || d.Online {
+		return true
+	}
+	now := h.clock.Now()
+	if now.Before(d.probe) {
+		return false
+	}
+	d.probe = now.Add(h.probeInterval)
+	return true
+}
+
+// device returns the state of the device at address. h.mu must be held.
+func (h *Health) device(address int) *trackedDevice {
+	d, ok := h.devices[address]
+	if !ok {
+		d = &trackedDevice{DeviceHealth: DeviceHealth{Address: address, Online: true}}
+		h.devices[address] = d
+	}
+	return d
+}
+
+// trackingTransactor records the results of the transactions of transactor in health.
+type trackingTransactor struct {
+	transactor Transactor
+	health     *Health
+	address    func(request []byte) int
+}
+
+func (t *trackingTransactor) Transact(ctx context.Context, request []byte, match func(Frame) bool) (Frame, error) {
+	f, err := t.transactor.Transact(ctx, request, match)
+	if match != nil && ctx.Err() == nil {
+		t.health.Record(t.address(request), err)
+	}
+	return f, err
+}
@@ -0,0 +1,144 @@
+package framereader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+func TestHealth(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	var events []DeviceHealth
+	health := NewHealthConfig(HealthConfig{
+		MaxFailures: 2,
+		Clock:       clock,
+		OnChange:    func(d DeviceHealth) { events = append(events, d) },
+	})
+
+	health.Record(2, nil)
+	health.Record(1, nil)
+	health.Record(1, ErrTimeout)
+	if !health.Online(1) || len(events) != 0 {
+		t.Error("expected device online after 1 failure: ", events)
+	}
+
+	clock.Advance(time.Second)
+	health.Record(1, ErrChecksum)
+	if health.Online(1) || len(events) != 1 || events[0].Online {
+		t.Error("expected device offline after 2 failures: ", events)
+	}
+	health.Record(1, ErrTimeout)
+	if len(events) != 1 {
+		t.Error("expected a single offline event: ", events)
+	}
+
+	health.Record(1, nil)
+	exp := DeviceHealth{
+		Address:     1,
+		Online:      true,
+		Successes:   2,
+		Timeouts:    2,
+		Errors:      1,
+		LastSuccess: epoch.Add(time.Second),
+		LastFailure: epoch.Add(time.Second),
+		LastError:   ErrTimeout,
+	}
+	if d := health.Device(1); !reflect.DeepEqual(d, exp) {
+		t.Errorf("expected %+v, got %+v", exp, d)
+	}
+	if len(events) != 2 || !events[1].Online {
+		t.Error("expected online event: ", events)
+	}
+
+	if devices := health.Devices(); len(devices) != 2 || devices[0].Address != 1 || devices[1].Address != 2 {
+		t.Error("expected devices 1 and 2: ", devices)
+	}
+	if d := health.Device(3); !d.Online || d.Successes != 0 {
+		t.Error("expected unknown device online: ", d)
+	}
+}
+
+func TestHealthProbe(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	health := NewHealthConfig(HealthConfig{MaxFailures: 1, ProbeInterval: time.Minute, Clock: clock})
+
+	if !health.probe(1) {
+		t.Error("expected unknown device to be polled")
+	}
+	health.Record(1, ErrTimeout)
+	if health.probe(1) {
+		t.Error("expected offline device not to be probed before the probe interval")
+	}
+
+	clock.Advance(time.Minute)
+	if !health.probe(1) {
+		t.Error("expected offline device to be probed after the probe interval")
+	}
+	if health.probe(1) {
+		t.Error("expected a single probe per probe interval")
+	}
+}
+
+func TestHealthTrack(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	transactor := &recordingTransactor{clock: clock, err: ErrTimeout}
+	health := NewHealthConfig(HealthConfig{Clock: clock})
+	tracked := health.Track(transactor, func(request []byte) int { return int(request[0]) })
+
+	if _, err := tracked.Transact(context.Background(), []byte{7}, MatchAll); err != ErrTimeout {
+		t.Error("expected timeout, got: ", err)
+	}
+	tracked.Transact(context.Background(), []byte{7}, nil)
+
+	transactor.fail(errors.New("broken"))
+	tracked.Transact(context.Background(), []byte{8}, MatchAll)
+
+	if d := health.Device(7); d.Timeouts != 1 || d.Failures != 1 {
+		t.Error("expected one recorded timeout: ", d)
+	}
+	if d := health.Device(8); d.Errors != 1 || d.LastError == nil {
+		t.Error("expected one recorded error: ", d)
+	}
+}
+
+func TestSchedulerHealth(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	transactor := &recordingTransactor{clock: clock, err: ErrTimeout}
+	events := make(chan DeviceHealth, 10)
+	health := NewHealthConfig(HealthConfig{
+		MaxFailures:   1,
+		ProbeInterval: time.Second,
+		Clock:         clock,
+		OnChange:      func(d DeviceHealth) { events <- d },
+	})
+	scheduler := NewSchedulerConfig(transactor, SchedulerConfig{Health: health, Clock: clock})
+
+	scheduler.Add(PollJob{Address: 1, Request: []byte{'a'}, Interval: 100 * time.Millisecond, Match: MatchAll})
+	stop := runScheduler(t, scheduler, transactor)
+
+	if d := <-events; d.Online {
+		t.Error("expected device offline after the first poll: ", d)
+	}
+
+	// the offline device is probed after the probe interval only
+	transactor.fail(nil)
+	for i := 0; i < 10; i++ {
+		clock.BlockUntilTimer(100 * time.Millisecond)
+		clock.Advance(100 * time.Millisecond)
+	}
+	if d := <-events; !d.Online || !d.LastSuccess.Equal(epoch.Add(time.Second)) {
+		t.Error("expected device online after the probe: ", d)
+	}
+	clock.BlockUntilTimer(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
+	clock.BlockUntilTimer(100 * time.Millisecond)
+
+	exp := []recordedPoll{{'a', 0}, {'a', time.Second}, {'a', 1100 * time.Millisecond}}
+	if polls := stop(); !reflect.DeepEqual(polls, exp) {
+		t.Errorf("expected polls %v, got %v", exp, polls)
+	}
+}
@@ -0,0 +1,83 @@
+// Package iec62056 reads meters via the optical interface of IEC 62056-21 (D0), protocol mode C.
+//
+// The serial port has to be configured for 300 baud, 7 data bits, even parity and 1 stop bit.
+package iec62056
+
+import (
+	"bytes"
+
+	"github.com/womat/framereader"
+)
+
+// Control characters of IEC 62056-21.
+const (
+	stx = 0x02
+	etx = 0x03
+	ack = 0x06
+)
+
+// framer separates the lines of the handshake and the data messages.
+type framer struct {
+	policy framereader.ChecksumPolicy
+}
+
+// NewFramer creates a framer for IEC 62056-21. A data message starts with STX and ends with ETX and the BCC,
+// the BCC is validated. Other messages, e.g. the identification, end with CR LF.
+// The inter frame delay is ignored.
+func NewFramer(policy framereader.ChecksumPolicy) framereader.Framer {
+	return framer{policy: policy}
+}
+
+// WithFramer configures the reader by NewFramer and a max frame size for data messages of common meters.
+// The timeout of the reader has to exceed the duration of the data readout.
+func WithFramer(policy framereader.ChecksumPolicy) framereader.Option {
+	return func(c *framereader.Config) {
+		c.Framer = NewFramer(policy)
+		c.MaxFrameSize = MaxMessageSize
+	}
+}
+
+// MaxMessageSize is the max frame size configured by WithFramer.
+const MaxMessageSize = 4096
+
+// Split returns the next line or data message. Data, which can't start a message, e.g. noise after
+// the change of the baud rate, is dropped. Messages start with '/' (request and identification), ACK or STX.
+func (f framer) Split(data []byte, _ bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, string([]byte{'/', stx, ack})); i != 0 {
+		if i < 0 {
+			i = len(data)
+		}
+		return i, nil, nil
+	}
+
+	if data[0] != stx {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			return i + 1, data[:i+1], nil
+		}
+		return 0, nil, nil
+	}
+
+	// the BCC follows ETX
+	i := bytes.IndexByte(data, etx)
+	if i < 0 || i+1 >= len(data) {
+		return 0, nil, nil
+	}
+	n := i + 2
+	if bcc(data[1:n-1]) == data[n-1] {
+		return n, data[:n], nil
+	}
+	if f.policy == framereader.ChecksumError {
+		return n, data[:n], framereader.ErrChecksum
+	}
+	return n, nil, nil
+}
+
+// bcc returns the block check character of data, which is the xor of all bytes.
+// It's calculated from the byte following STX up to and including ETX.
+func bcc(data []byte) byte {
+	var b byte
+	for _, c := range data {
+		b ^= c
+	}
+	return b
+}
@@ -0,0 +1,135 @@
+package iec62056
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/womat/framereader"
+	"github.com/womat/framereader/framereadertest/transporttest"
+)
+
+// dataMessage returns the data message of block including STX, ETX and BCC.
+func dataMessage(block string) []byte {
+	m := append([]byte{stx}, block...)
+	m = append(m, etx)
+	return append(m, bcc(m[1:]))
+}
+
+const dataBlock = "0.0.0(12345678)\r\n1.8.0(001234.567*kWh)\r\n1-0:2.8.0*255(000012.3*kWh)\r\nC.1(1)(2)F.F(00)\r\n!\r\n"
+
+func TestFramer(t *testing.T) {
+	valid := dataMessage(dataBlock)
+	invalid := append([]byte(nil), valid...)
+	invalid[5]++
+
+	var data []byte
+	data = append(data, "/ISk5MT174-0001\r\n"...)
+	data = append(data, invalid...)
+	data = append(data, 0x00, 0x7f) // noise after the change of the baud rate
+	data = append(data, valid...)
+
+	reader := framereader.New(iotest.OneByteReader(bytes.NewReader(data)), framereader.WithTimeout(time.Second), WithFramer(framereader.ChecksumDrop)).(*framereader.Reader)
+
+	for _, exp := range [][]byte{[]byte("/ISk5MT174-0001\r\n"), valid} {
+		f, err := reader.ReadFrame()
+		if err != nil || !bytes.Equal(f.Data, exp) {
+			t.Errorf("expected %q, got %q (err: %v)", exp, f.Data, err)
+		}
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+
+	if _, frame, err := NewFramer(framereader.ChecksumError).Split(invalid, false); !bytes.Equal(frame, invalid) || err != framereader.ErrChecksum {
+		t.Errorf("expected checksum error, got %q, %v", frame, err)
+	}
+}
+
+func TestParseIdentification(t *testing.T) {
+	id, err := ParseIdentification([]byte("/ISk5MT174-0001\r\n"))
+	if err != nil || id.Manufacturer != "ISk" || id.BaudRate != 9600 || id.Identification != "MT174-0001" {
+		t.Errorf("unexpected identification: %+v (err: %v)", id, err)
+	}
+
+	id, err = ParseIdentification([]byte("/LGZ\\2ZMD3104107.B32\r\n"))
+	if err != nil || id.BaudRate != 0 || id.Identification != "2ZMD3104107.B32" {
+		t.Errorf("expected identification of mode B: %+v (err: %v)", id, err)
+	}
+
+	if _, err = ParseIdentification([]byte("ISk5\r\n")); err != ErrInvalidIdentification {
+		t.Error("expected invalid identification, got: ", err)
+	}
+}
+
+func TestParseDataMessage(t *testing.T) {
+	sets, err := ParseDataMessage(dataMessage(dataBlock))
+	if err != nil {
+		t.Fatal("parse failed: ", err)
+	}
+
+	exp := []DataSet{
+		{"0.0.0", []Value{{"12345678", ""}}},
+		{"1.8.0", []Value{{"001234.567", "kWh"}}},
+		{"1-0:2.8.0*255", []Value{{"000012.3", "kWh"}}},
+		{"C.1", []Value{{"1", ""}, {"2", ""}}},
+		{"F.F", []Value{{"00", ""}}},
+	}
+	if !reflect.DeepEqual(sets, exp) {
+		t.Errorf("expected %v, got %v", exp, sets)
+	}
+	if v, err := sets[1].Values[0].Float(); err != nil || v != 1234.567 {
+		t.Error("expected 1234.567: ", v, err)
+	}
+
+	if _, err = ParseDataMessage(dataMessage("1.8.0(0012\r\n!\r\n")); err != ErrInvalidDataMessage {
+		t.Error("expected invalid data message, got: ", err)
+	}
+}
+
+func TestReadout(t *testing.T) {
+	var _ Transport = (*framereader.ReadWriteCloser)(nil)
+
+	transport := &transporttest.Transport{Echo: true, Respond: func(request []byte) [][]byte {
+		switch request[0] {
+		case '/':
+			return [][]byte{[]byte("/ISk5MT174-0001\r\n")}
+		case ack:
+			return [][]byte{dataMessage(dataBlock)}
+		}
+		return nil
+	}}
+
+	var baudrate int
+	id, sets, err := Readout(context.Background(), transport, Config{
+		Address:        "12345678",
+		SwitchBaudRate: func(b int) error { baudrate = b; return nil },
+	})
+	if err != nil {
+		t.Fatal("readout failed: ", err)
+	}
+
+	if id.Manufacturer != "ISk" || len(sets) != 5 || sets[1].Address != "1.8.0" {
+		t.Errorf("unexpected readout: %+v, %v", id, sets)
+	}
+	if baudrate != 9600 {
+		t.Error("expected switch to 9600 baud: ", baudrate)
+	}
+	exp := [][]byte{[]byte("/?12345678!\r\n"), {ack, '0', '5', '0', '\r', '\n'}}
+	if !reflect.DeepEqual(transport.Written(), exp) {
+		t.Errorf("expected requests %q, got %q", exp, transport.Written())
+	}
+
+	// without baud rate switch, the readout is requested at 300 baud
+	transport = &transporttest.Transport{Echo: true, Respond: transport.Respond}
+	if _, _, err = Readout(context.Background(), transport, Config{}); err != nil {
+		t.Error("readout failed: ", err)
+	}
+	if len(transport.Written()) != 2 || transport.Written()[1][2] != '0' {
+		t.Errorf("expected acknowledgement for 300 baud, got %q", transport.Written())
+	}
+}
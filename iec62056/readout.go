@@ -0,0 +1,192 @@
+package iec62056
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/womat/framereader"
+)
+
+var (
+	// ErrInvalidIdentification is returned, if the identification message of the meter can't be parsed.
+	ErrInvalidIdentification = errors.New("iec62056: invalid identification")
+
+	// ErrInvalidDataMessage is returned, if the data message of the meter can't be parsed.
+	ErrInvalidDataMessage = errors.New("iec62056: invalid data message")
+)
+
+// Transport sends requests and receives the response frames.
+// It's implemented by *framereader.ReadWriteCloser configured by WithFramer.
+type Transport interface {
+	WriteContext(ctx context.Context, buffer []byte) (int, error)
+	ReadFrameContext(ctx context.Context) (framereader.Frame, error)
+}
+
+// baudRates are the baud rates of mode C identified by the characters '0' to '6'.
+var baudRates = []int{300, 600, 1200, 2400, 4800, 9600, 19200}
+
+// Identification is the identification message sent by the meter after the sign on, e.g. "/ISk5MT174-0001".
+type Identification struct {
+	// Manufacturer is the three letter manufacturer id.
+	Manufacturer string
+
+	// BaudRate is the max baud rate supported by the meter in mode C. It's 0 for other modes.
+	BaudRate int
+
+	// Identification is the identification of the device type, including enhanced capability sequences.
+	Identification string
+
+	baud byte // baud rate character
+}
+
+// ParseIdentification parses the identification message /XXXZIdentification CR LF.
+func ParseIdentification(message []byte) (Identification, error) {
+	line := strings.TrimRight(string(message), "\r\n")
+	if len(line) < 5 || line[0] != '/' {
+		return Identification{}, ErrInvalidIdentification
+	}
+
+	id := Identification{Manufacturer: line[1:4], Identification: line[5:], baud: line[4]}
+	if id.baud >= '0' && int(id.baud-'0') < len(baudRates) {
+		id.BaudRate = baudRates[id.baud-'0']
+	}
+	return id, nil
+}
+
+// DataSet is a line of the data message, e.g. "1.8.0(001234.567*kWh)".
+type DataSet struct {
+	// Address is the OBIS code, e.g. "1.8.0" or "1-0:1.8.0*255".
+	Address string
+
+	// Values are the values in brackets. Most data sets have a single value.
+	Values []Value
+}
+
+// Value is a value of a data set, e.g. "001234.567*kWh".
+type Value struct {
+	Value string
+	Unit  string // empty, if the value has no unit
+}
+
+// Float returns the numeric value.
+func (v Value) Float() (float64, error) {
+	return strconv.ParseFloat(v.Value, 64)
+}
+
+// ParseDataMessage parses the data sets of a data message STX data block ETX BCC.
+// The data block ends with the end line "!".
+func ParseDataMessage(message []byte) ([]DataSet, error) {
+	if len(message) < 3 || message[0] != stx || message[len(message)-2] != etx {
+		return nil, ErrInvalidDataMessage
+	}
+
+	var sets []DataSet
+	for _, line := range strings.Split(string(message[1:len(message)-2]), "\r\n") {
+		if line == "" || line == "!" {
+			continue
+		}
+
+		s, err := parseDataLine(line)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, s...)
+	}
+	return sets, nil
+}
+
+// parseDataLine parses the data sets of a line. A line may contain multiple data sets, e.g. "1.8.0(1*kWh)2.8.0(2*kWh)".
+func parseDataLine(line string) ([]DataSet, error) {
+	var sets []DataSet
+	for line != "" {
+		i := strings.IndexByte(line, '(')
+		if i < 0 {
+			return nil, ErrInvalidDataMessage
+		}
+		set := DataSet{Address: line[:i]}
+		line = line[i:]
+
+		for strings.HasPrefix(line, "(") {
+			end := strings.IndexByte(line, ')')
+			if end < 0 {
+				return nil, ErrInvalidDataMessage
+			}
+			value, unit, _ := strings.Cut(line[1:end], "*")
+			set.Values = append(set.Values, Value{Value: value, Unit: unit})
+			line = line[end+1:]
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// Config configures the data readout.
+type Config struct {
+	// Address is the device address sent with the sign on. If Address is empty, any meter answers.
+	Address string
+
+	// SwitchBaudRate is called to switch the serial port to the baud rate proposed by the meter,
+	// after the acknowledgement has been written. It should wait, until the acknowledgement is transmitted.
+	// If SwitchBaudRate is nil, the readout is requested at 300 baud.
+	SwitchBaudRate func(baudrate int) error
+}
+
+// Readout reads the data message of a meter in protocol mode C:
+// The sign on /?Address! is sent, the identification is received and acknowledged
+// with the request of a data readout, before the data message is received.
+// Echos of the requests, e.g. of a bidirectional optical head, are ignored.
+func Readout(ctx context.Context, transport Transport, config Config) (Identification, []DataSet, error) {
+	request := []byte("/?" + config.Address + "!\r\n")
+	if _, err := transport.WriteContext(ctx, request); err != nil {
+		return Identification{}, nil, err
+	}
+
+	message, err := readResponse(ctx, transport, request)
+	if err != nil {
+		return Identification{}, nil, err
+	}
+	id, err := ParseIdentification(message)
+	if err != nil {
+		return id, nil, err
+	}
+
+	if id.BaudRate != 0 {
+		baud, baudrate := byte('0'), baudRates[0]
+		if config.SwitchBaudRate != nil {
+			baud, baudrate = id.baud, id.BaudRate
+		}
+
+		// acknowledge with protocol control '0' (normal) and mode '0' (data readout)
+		request = []byte{ack, '0', baud, '0', '\r', '\n'}
+		if _, err = transport.WriteContext(ctx, request); err != nil {
+			return id, nil, err
+		}
+		if config.SwitchBaudRate != nil {
+			if err = config.SwitchBaudRate(baudrate); err != nil {
+				return id, nil, err
+			}
+		}
+	}
+
+	if message, err = readResponse(ctx, transport, request); err != nil {
+		return id, nil, err
+	}
+	sets, err := ParseDataMessage(message)
+	return id, sets, err
+}
+
+// readResponse returns the next frame, which isn't the echo of request.
+func readResponse(ctx context.Context, transport Transport, request []byte) ([]byte, error) {
+	for {
+		f, err := transport.ReadFrameContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(f.Data, request) {
+			return f.Data, nil
+		}
+	}
+}
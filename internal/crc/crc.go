@@ -0,0 +1,48 @@
+// Package crc implements the 16 bit CRCs used by the field bus protocols of framereader.
+package crc
+
+// table is a 256-word table of a reflected 16 bit CRC.
+type table [256]uint16
+
+// makeTable creates the table of the reflected polynomial poly.
+func makeTable(poly uint16) *table {
+	t := new(table)
+	for i := range t {
+		crc := uint16(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ poly
+			} else {
+				crc >>= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}
+
+var (
+	// modbusTable is the table of CRC-16/MODBUS, polynomial 0x8005 reflected.
+	modbusTable = makeTable(0xa001)
+
+	// x25Table is the table of CRC-16/X-25 (FCS-16 of HDLC), polynomial 0x1021 reflected.
+	x25Table = makeTable(0x8408)
+)
+
+// update adds data to crc.
+func update(crc uint16, t *table, data []byte) uint16 {
+	for _, b := range data {
+		crc = crc>>8 ^ t[byte(crc)^b]
+	}
+	return crc
+}
+
+// Modbus returns the CRC-16/MODBUS of data. It's transmitted low byte first.
+func Modbus(data []byte) uint16 {
+	return update(0xffff, modbusTable, data)
+}
+
+// X25 returns the CRC-16/X-25 of data, which is used by SML and as FCS-16 of HDLC.
+func X25(data []byte) uint16 {
+	return ^update(0xffff, x25Table, data)
+}
@@ -0,0 +1,14 @@
+package crc
+
+import "testing"
+
+func TestCRC(t *testing.T) {
+	check := []byte("123456789")
+
+	if crc := Modbus(check); crc != 0x4b37 {
+		t.Errorf("Modbus: expected 0x4b37, got %#04x", crc)
+	}
+	if crc := X25(check); crc != 0x906e {
+		t.Errorf("X25: expected 0x906e, got %#04x", crc)
+	}
+}
@@ -0,0 +1,145 @@
+package framereader
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// Log levels used in addition to the levels of package log/slog.
+const (
+	// LevelTrace is used for hex dumps of the received chunks.
+	LevelTrace = slog.Level(-8)
+
+	// LevelFatal is used for errors forcing the shutdown of the application.
+	LevelFatal = slog.Level(12)
+)
+
+// hexdump formats data as hex string, if the log record is written.
+type hexdump []byte
+
+func (h hexdump) LogValue() slog.Value {
+	return slog.StringValue(hex.EncodeToString(h))
+}
+
+// legacyHandler passes log records to the package loggers configured by SetDebug.
+// It's used, if no logger is configured for a Reader.
+type legacyHandler struct {
+	prefix string // attributes added by WithAttrs, formatted as key=value pairs
+	group  string // group added by WithGroup, used as key prefix
+}
+
+// legacyLogger returns the package logger for level.
+func legacyLogger(level slog.Level) *log.Logger {
+	switch {
+	case level >= LevelFatal:
+		return fatallog
+	case level >= slog.LevelError:
+		return errorlog
+	case level >= slog.LevelWarn:
+		return warninglog
+	case level >= slog.LevelInfo:
+		return infolog
+	case level >= slog.LevelDebug:
+		return debuglog
+	default:
+		return tracelog
+	}
+}
+
+func (h legacyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return legacyLogger(level).Writer() != io.Discard
+}
+
+func (h legacyHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		fmt.Fprintf(&b, "%v:%v: ", filepath.Base(frame.File), frame.Line)
+	}
+
+	b.WriteString(r.Message)
+	b.WriteString(h.prefix)
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&b, h.group, a)
+		return true
+	})
+
+	l := legacyLogger(r.Level)
+	if l.Writer() == io.Discard {
+		// the level is enabled by SetLogLevel of the reader, but not by SetDebug
+		l = log.New(debugWriter, l.Prefix(), l.Flags())
+	}
+	return l.Output(0, b.String())
+}
+
+func (h legacyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	for _, a := range attrs {
+		h.appendAttr(&b, h.group, a)
+	}
+	h.prefix = b.String()
+	return h
+}
+
+func (h legacyHandler) WithGroup(name string) slog.Handler {
+	if name != "" {
+		h.group += name + "."
+	}
+	return h
+}
+
+// appendAttr formats a as key=value pair.
+func (h legacyHandler) appendAttr(b *strings.Builder, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(b, group, ga)
+		}
+		return
+	}
+
+	fmt.Fprintf(b, " %v%v=%v", group, a.Key, a.Value)
+}
+
+// levelHandler overrides the level of handler, if a level is set.
+// The level can be changed at runtime, e.g. to enable hex dumps for a single port.
+type levelHandler struct {
+	handler slog.Handler
+	level   *atomic.Pointer[slog.Leveler]
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if l := h.level.Load(); l != nil {
+		return level >= (*l).Level()
+	}
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{handler: h.handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{handler: h.handler.WithGroup(name), level: h.level}
+}
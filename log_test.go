@@ -0,0 +1,162 @@
+package framereader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+// logBuffer is a bytes.Buffer safe for concurrent use.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns the JSON log records written so far.
+func (b *logBuffer) records(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(b.buf.Bytes()), []byte("\n")) {
+		var record map[string]interface{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatal("invalid log record: ", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	var buf logBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: LevelTrace})).With("port", "com1")
+
+	clock := framereadertest.NewClock(epoch)
+	port := framereadertest.NewPort()
+	rw := NewReadWriterConfig(port, Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Clock: clock, Logger: logger})
+	defer rw.reader.Close()
+	defer port.Close()
+
+	clock.BlockUntilTimer(10 * time.Millisecond)
+	sendChunk(clock, port, 10*time.Millisecond, []byte{1, 2})
+	clock.Advance(10 * time.Millisecond)
+
+	if _, err := rw.ReadFrame(); err != nil {
+		t.Error("read failed: ", err)
+	}
+
+	var frame map[string]interface{}
+	for _, record := range buf.records(t) {
+		if record["port"] != "com1" {
+			t.Error("expected port attribute: ", record)
+		}
+		if record["msg"] == "read new frame" {
+			frame = record
+		}
+	}
+
+	if frame == nil {
+		t.Fatal("expected frame to be logged")
+	}
+	if frame["level"] != "DEBUG" || frame["direction"] != "rx" || frame["data"] != "0102" {
+		t.Error("unexpected frame record: ", frame)
+	}
+}
+
+func TestLegacyHandler(t *testing.T) {
+	var buf bytes.Buffer
+	SetDebug(&buf, Standard|Debug)
+	defer SetDebug(os.Stderr, Standard|Debug)
+
+	logger := slog.New(legacyHandler{}).With("port", "com1").WithGroup("frame")
+	logger.Debug("read new frame", "data", hexdump{1, 2})
+	logger.Log(context.Background(), LevelTrace, "not written")
+
+	out := buf.String()
+	if !strings.Contains(out, "DEBUG: log_test.go:") || !strings.HasSuffix(out, "read new frame port=com1 frame.data=0102\n") {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestSetDebug(t *testing.T) {
+	defer SetDebug(os.Stderr, Standard|Debug)
+
+	tests := []struct {
+		flag     int
+		logger   **log.Logger
+		prefix   string
+		disabled []**log.Logger
+	}{
+		{Info, &infolog, "INFO: ", []**log.Logger{&warninglog, &errorlog, &fatallog}},
+		{Warning, &warninglog, "WARNING: ", []**log.Logger{&infolog, &errorlog, &fatallog}},
+		{Error, &errorlog, "ERROR: ", []**log.Logger{&infolog, &warninglog, &fatallog}},
+		{Fatal, &fatallog, "FATAL: ", []**log.Logger{&infolog, &warninglog, &errorlog}},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		SetDebug(&buf, tt.flag)
+
+		if (*tt.logger).Writer() != &buf || (*tt.logger).Prefix() != tt.prefix {
+			t.Errorf("%q: expected logger to be enabled", tt.prefix)
+		}
+		for _, l := range tt.disabled {
+			if (*l).Writer() != io.Discard {
+				t.Errorf("%q: expected %q to be disabled", tt.prefix, (*l).Prefix())
+			}
+		}
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	var buf logBuffer
+	SetDebug(&buf, Standard)
+	defer SetDebug(os.Stderr, Standard|Debug)
+
+	port := framereadertest.NewPort()
+	rw := NewReadWriterConfig(port, Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Logger: slog.New(legacyHandler{}).With("port", "com1")})
+	defer rw.reader.Close()
+	defer port.Close()
+
+	written := func() string {
+		buf.mu.Lock()
+		defer buf.mu.Unlock()
+		s := buf.buf.String()
+		buf.buf.Reset()
+		return s
+	}
+
+	rw.Write([]byte{1, 2})
+	if s := written(); s != "" {
+		t.Errorf("expected no trace output, got %q", s)
+	}
+
+	rw.SetLogLevel(LevelTrace)
+	rw.Write([]byte{1, 2})
+	if s := written(); !strings.Contains(s, "TRACE: ") || !strings.HasSuffix(s, "write to serial port port=com1 direction=tx data=0102\n") {
+		t.Errorf("expected trace output, got %q", s)
+	}
+
+	rw.SetLogLevel(nil)
+	rw.Write([]byte{1, 2})
+	if s := written(); s != "" {
+		t.Errorf("expected no trace output after reset, got %q", s)
+	}
+}
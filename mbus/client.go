@@ -0,0 +1,136 @@
+package mbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/womat/framereader"
+)
+
+// Control fields of the link layer.
+const (
+	controlSndNKE = 0x40
+	controlReqUD2 = 0x5b
+	controlRspUD  = 0x08
+	fcb           = 0x20 // frame count bit of requests
+	acdDFC        = 0x30 // access demand and data flow control bits of responses
+)
+
+// Special primary addresses.
+const (
+	// AddressNetworkLayer addresses the slave selected by secondary addressing.
+	AddressNetworkLayer byte = 0xfd
+
+	// AddressBroadcastReply is received by all slaves, which reply. It's used, if only one slave is connected.
+	AddressBroadcastReply byte = 0xfe
+
+	// AddressBroadcast is received by all slaves, which don't reply.
+	AddressBroadcast byte = 0xff
+)
+
+// ErrInvalidFrame is returned, if a frame can't be parsed or doesn't answer the request.
+var ErrInvalidFrame = errors.New("mbus: invalid frame")
+
+// Frame is a long or control frame.
+type Frame struct {
+	Control            byte
+	Address            byte
+	ControlInformation byte
+	Data               []byte // empty for control frames
+}
+
+// ParseFrame parses a long or control frame returned by the framer of NewFramer.
+func ParseFrame(data []byte) (Frame, error) {
+	if len(data) < 9 || data[0] != longStart || int(data[1])+6 != len(data) {
+		return Frame{}, ErrInvalidFrame
+	}
+	return Frame{
+		Control:            data[4],
+		Address:            data[5],
+		ControlInformation: data[6],
+		Data:               data[7 : len(data)-2],
+	}, nil
+}
+
+// Client is an M-Bus master polling slaves by their primary address.
+// It's safe for concurrent use, requests are serialized.
+// The transactor is e.g. *framereader.ReadWriteCloser configured by WithFramer.
+type Client struct {
+	mu         sync.Mutex
+	transactor framereader.Transactor
+	fcb        map[byte]bool // frame count bit of the next REQ_UD2 per address
+}
+
+// NewClient creates a client sending its requests via transactor.
+func NewClient(transactor framereader.Transactor) *Client {
+	return &Client{transactor: transactor, fcb: make(map[byte]bool)}
+}
+
+// SndNKE initializes the slave at address. The slave acknowledges with a single character,
+// unless address is AddressBroadcast.
+func (c *Client) SndNKE(ctx context.Context, address byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var match func(framereader.Frame) bool
+	if address != AddressBroadcast {
+		match = matchResponse(address)
+	}
+
+	f, err := c.transactor.Transact(ctx, shortFrame(controlSndNKE, address), match)
+	if err != nil {
+		return err
+	}
+	// the first REQ_UD2 after the initialization has the frame count bit set
+	c.fcb[address] = true
+	if address != AddressBroadcast && len(f.Data) != 1 {
+		return ErrInvalidFrame
+	}
+	return nil
+}
+
+// ReqUD2 requests the user data of class 2 of the slave at address, e.g. the meter values.
+// The frame count bit is toggled after each successful request.
+func (c *Client) ReqUD2(ctx context.Context, address byte) (Frame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	control := byte(controlReqUD2)
+	if c.fcb[address] {
+		control |= fcb
+	}
+	response, err := c.transactor.Transact(ctx, shortFrame(control, address), matchResponse(address))
+	if err != nil {
+		return Frame{}, err
+	}
+	f, err := ParseFrame(response.Data)
+	if err != nil {
+		return Frame{}, err
+	}
+	if f.Control&^acdDFC != controlRspUD {
+		return Frame{}, ErrInvalidFrame
+	}
+
+	c.fcb[address] = !c.fcb[address]
+	return f, nil
+}
+
+// matchResponse returns a matcher for the single character frame and the long frames of address.
+// Long frames of other slaves are discarded.
+func matchResponse(address byte) func(framereader.Frame) bool {
+	return func(f framereader.Frame) bool {
+		switch {
+		case len(f.Data) == 1:
+			return f.Data[0] == singleCharacter
+		case len(f.Data) > 5 && f.Data[0] == longStart:
+			return address == AddressBroadcastReply || f.Data[5] == address
+		}
+		return false
+	}
+}
+
+// shortFrame returns the short frame of control and address.
+func shortFrame(control, address byte) []byte {
+	return []byte{shortStart, control, address, control + address, stop}
+}
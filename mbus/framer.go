@@ -0,0 +1,88 @@
+// Package mbus implements the data link layer of the wired M-Bus (EN 13757-2) on top of the frame readers of package framereader.
+package mbus
+
+import (
+	"github.com/womat/framereader"
+)
+
+// Start and stop characters of the frames.
+const (
+	singleCharacter = 0xe5
+	shortStart      = 0x10
+	longStart       = 0x68
+	stop            = 0x16
+)
+
+// MaxFrameSize is the size of a long frame with 255 bytes of control, address, control information and data.
+const MaxFrameSize = 255 + 6
+
+// framer separates the single character, short and long frames of M-Bus.
+type framer struct {
+	policy framereader.ChecksumPolicy
+}
+
+// NewFramer creates a framer for M-Bus. The frames are recognized by their structure, the checksum is validated.
+// Bytes, which don't start a frame, are dropped. The inter frame delay is ignored.
+func NewFramer(policy framereader.ChecksumPolicy) framereader.Framer {
+	return framer{policy: policy}
+}
+
+// WithFramer configures the reader by NewFramer and a max frame size of MaxFrameSize.
+func WithFramer(policy framereader.ChecksumPolicy) framereader.Option {
+	return func(c *framereader.Config) {
+		c.Framer = NewFramer(policy)
+		c.MaxFrameSize = MaxFrameSize
+	}
+}
+
+// Split returns the next frame, if it's received completely.
+func (f framer) Split(data []byte, _ bool) (int, []byte, error) {
+	var n int // size of the frame
+	var fields []byte
+
+	switch data[0] {
+	case singleCharacter:
+		return 1, data[:1], nil
+	case shortStart:
+		n = 5
+		if len(data) < n {
+			return 0, nil, nil
+		}
+		fields = data[1:3]
+	case longStart:
+		if len(data) < 4 {
+			return 0, nil, nil
+		}
+		if data[1] != data[2] || data[3] != longStart || data[1] < 3 {
+			// resynchronize with the next byte
+			return 1, nil, nil
+		}
+		n = int(data[1]) + 6
+		if len(data) < n {
+			return 0, nil, nil
+		}
+		fields = data[4 : n-2]
+	default:
+		return 1, nil, nil
+	}
+
+	if data[n-1] != stop {
+		return 1, nil, nil
+	}
+	if checksum(fields) == data[n-2] {
+		return n, data[:n], nil
+	}
+	if f.policy == framereader.ChecksumError {
+		return n, data[:n], framereader.ErrChecksum
+	}
+	return n, nil, nil
+}
+
+// checksum returns the arithmetic sum of the control, address, control information and data fields.
+func checksum(fields []byte) byte {
+	var cs byte
+	for _, b := range fields {
+		cs += b
+	}
+	return cs
+}
@@ -0,0 +1,102 @@
+package mbus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/womat/framereader"
+	"github.com/womat/framereader/framereadertest/transporttest"
+)
+
+// longFrame returns the long frame of the fields control, address, control information and data.
+func longFrame(fields ...byte) []byte {
+	f := []byte{longStart, byte(len(fields)), byte(len(fields)), longStart}
+	f = append(f, fields...)
+	return append(f, checksum(fields), stop)
+}
+
+func TestFramer(t *testing.T) {
+	rsp := longFrame(0x08, 0x05, 0x72, 0x78, 0x56, 0x34, 0x12)
+	invalid := append([]byte(nil), rsp...)
+	invalid[7]++
+
+	var data []byte
+	data = append(data, 0x00, 0x68, 0x01) // garbage
+	data = append(data, singleCharacter)
+	data = append(data, shortFrame(controlSndNKE, 5)...)
+	data = append(data, invalid...)
+	data = append(data, rsp...)
+
+	reader := framereader.New(iotest.OneByteReader(bytes.NewReader(data)), framereader.WithTimeout(time.Second), WithFramer(framereader.ChecksumDrop)).(*framereader.Reader)
+
+	for _, exp := range [][]byte{{singleCharacter}, shortFrame(controlSndNKE, 5), rsp} {
+		f, err := reader.ReadFrame()
+		if err != nil || !bytes.Equal(f.Data, exp) {
+			t.Errorf("expected % x, got % x (err: %v)", exp, f.Data, err)
+		}
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+
+	if _, frame, err := NewFramer(framereader.ChecksumError).Split(invalid, false); !bytes.Equal(frame, invalid) || err != framereader.ErrChecksum {
+		t.Errorf("expected checksum error, got % x, %v", frame, err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var _ framereader.Transactor = (*framereader.ReadWriteCloser)(nil)
+	ctx := context.Background()
+
+	transport := &transporttest.Transport{Respond: func(request []byte) [][]byte {
+		switch request[1] &^ fcb {
+		case controlSndNKE:
+			return [][]byte{{singleCharacter}}
+		case controlReqUD2:
+			return [][]byte{
+				longFrame(0x08, 0x06, 0x72, 0x00), // other slave
+				longFrame(0x08, request[2], 0x72, 0x78, 0x56, 0x34, 0x12),
+			}
+		}
+		return nil
+	}}
+	c := NewClient(transport)
+
+	if err := c.SndNKE(ctx, 5); err != nil {
+		t.Error("SND_NKE failed: ", err)
+	}
+	for i := 0; i < 2; i++ {
+		f, err := c.ReqUD2(ctx, 5)
+		exp := Frame{Control: 0x08, Address: 5, ControlInformation: 0x72, Data: []byte{0x78, 0x56, 0x34, 0x12}}
+		if err != nil || !reflect.DeepEqual(f, exp) {
+			t.Errorf("unexpected response: %+v (err: %v)", f, err)
+		}
+	}
+
+	// the frame count bit is set after SND_NKE and toggled afterwards
+	exp := [][]byte{{0x10, 0x40, 0x05, 0x45, 0x16}, {0x10, 0x7b, 0x05, 0x80, 0x16}, {0x10, 0x5b, 0x05, 0x60, 0x16}}
+	if !reflect.DeepEqual(transport.Written(), exp) {
+		t.Errorf("expected requests % x, got % x", exp, transport.Written())
+	}
+
+	if err := c.SndNKE(ctx, AddressBroadcast); err != nil {
+		t.Error("broadcast failed: ", err)
+	}
+	if _, err := c.ReqUD2(ctx, 7); err != nil {
+		t.Error("REQ_UD2 failed: ", err)
+	}
+
+	transport.Respond = func([]byte) [][]byte { return [][]byte{{singleCharacter}} }
+	if _, err := c.ReqUD2(ctx, 5); err != ErrInvalidFrame {
+		t.Error("expected invalid frame, got: ", err)
+	}
+	transport.Respond = func([]byte) [][]byte { return nil }
+	if err := c.SndNKE(ctx, 5); err != framereader.ErrTimeout {
+		t.Error("expected timeout, got: ", err)
+	}
+}
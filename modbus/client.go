@@ -0,0 +1,207 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+
+	"github.com/womat/framereader"
+)
+
+// Client is a Modbus RTU master. It's safe for concurrent use, if the transactor is,
+// e.g. *framereader.ReadWriteCloser serializes the transactions.
+type Client struct {
+	transactor framereader.Transactor
+}
+
+// NewClient creates a client sending its requests via transactor.
+func NewClient(transactor framereader.Transactor) *Client {
+	return &Client{transactor: transactor}
+}
+
+// ReadCoils reads quantity coils starting at address (function code 1).
+func (c *Client) ReadCoils(ctx context.Context, slave byte, address, quantity uint16) ([]bool, error) {
+	return c.readBits(ctx, slave, FuncReadCoils, address, quantity)
+}
+
+// ReadDiscreteInputs reads quantity discrete inputs starting at address (function code 2).
+func (c *Client) ReadDiscreteInputs(ctx context.Context, slave byte, address, quantity uint16) ([]bool, error) {
+	return c.readBits(ctx, slave, FuncReadDiscreteInputs, address, quantity)
+}
+
+// ReadHoldingRegisters reads quantity holding registers starting at address (function code 3).
+func (c *Client) ReadHoldingRegisters(ctx context.Context, slave byte, address, quantity uint16) ([]uint16, error) {
+	return c.readRegisters(ctx, slave, FuncReadHoldingRegisters, address, quantity)
+}
+
+// ReadInputRegisters reads quantity input registers starting at address (function code 4).
+func (c *Client) ReadInputRegisters(ctx context.Context, slave byte, address, quantity uint16) ([]uint16, error) {
+	return c.readRegisters(ctx, slave, FuncReadInputRegisters, address, quantity)
+}
+
+// WriteSingleCoil writes the coil at address (function code 5).
+func (c *Client) WriteSingleCoil(ctx context.Context, slave byte, address uint16, value bool) error {
+	var v uint16
+	if value {
+		v = 0xff00
+	}
+	return c.writeSingle(ctx, slave, FuncWriteSingleCoil, address, v)
+}
+
+// WriteSingleRegister writes the holding register at address (function code 6).
+func (c *Client) WriteSingleRegister(ctx context.Context, slave byte, address, value uint16) error {
+	return c.writeSingle(ctx, slave, FuncWriteSingleRegister, address, value)
+}
+
+// WriteMultipleCoils writes the coils starting at address (function code 15).
+func (c *Client) WriteMultipleCoils(ctx context.Context, slave byte, address uint16, values []bool) error {
+	if len(values) < 1 || len(values) > MaxWriteBits {
+		return ErrInvalidQuantity
+	}
+	return c.writeMultiple(ctx, slave, FuncWriteMultipleCoils, address, uint16(len(values)), encodeBits(values))
+}
+
+// WriteMultipleRegisters writes the holding registers starting at address (function code 16).
+func (c *Client) WriteMultipleRegisters(ctx context.Context, slave byte, address uint16, values []uint16) error {
+	if len(values) < 1 || len(values) > MaxWriteRegisters {
+		return ErrInvalidQuantity
+	}
+	return c.writeMultiple(ctx, slave, FuncWriteMultipleRegisters, address, uint16(len(values)), encodeRegisters(values))
+}
+
+// ReadWriteMultipleRegisters writes values to the holding registers starting at writeAddress and then
+// reads readQuantity holding registers starting at readAddress in a single transaction (function code 23).
+func (c *Client) ReadWriteMultipleRegisters(ctx context.Context, slave byte, readAddress, readQuantity, writeAddress uint16, values []uint16) ([]uint16, error) {
+	if readQuantity < 1 || readQuantity > MaxReadRegisters || len(values) < 1 || len(values) > MaxRWRegisters {
+		return nil, ErrInvalidQuantity
+	}
+	if slave == BroadcastAddress {
+		return nil, ErrBroadcast
+	}
+
+	data := encodeRegisters(values)
+	pdu := make([]byte, 0, 10+len(data))
+	pdu = append(pdu, FuncReadWriteMultipleRegisters)
+	pdu = binary.BigEndian.AppendUint16(pdu, readAddress)
+	pdu = binary.BigEndian.AppendUint16(pdu, readQuantity)
+	pdu = binary.BigEndian.AppendUint16(pdu, writeAddress)
+	pdu = binary.BigEndian.AppendUint16(pdu, uint16(len(values)))
+	pdu = append(pdu, byte(len(data)))
+	pdu = append(pdu, data...)
+
+	response, err := c.transact(ctx, slave, pdu)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) != 2+2*int(readQuantity) || int(response[1]) != 2*int(readQuantity) {
+		return nil, ErrInvalidResponse
+	}
+	return decodeRegisters(response[2:]), nil
+}
+
+// readBits reads quantity coils or discrete inputs.
+func (c *Client) readBits(ctx context.Context, slave byte, function byte, address, quantity uint16) ([]bool, error) {
+	if quantity < 1 || quantity > MaxReadBits {
+		return nil, ErrInvalidQuantity
+	}
+	if slave == BroadcastAddress {
+		return nil, ErrBroadcast
+	}
+
+	response, err := c.transact(ctx, slave, requestPDU(function, address, quantity))
+	if err != nil {
+		return nil, err
+	}
+	n := (int(quantity) + 7) / 8
+	if len(response) != 2+n || int(response[1]) != n {
+		return nil, ErrInvalidResponse
+	}
+	return decodeBits(response[2:], int(quantity)), nil
+}
+
+// readRegisters reads quantity holding or input registers.
+func (c *Client) readRegisters(ctx context.Context, slave byte, function byte, address, quantity uint16) ([]uint16, error) {
+	if quantity < 1 || quantity > MaxReadRegisters {
+		return nil, ErrInvalidQuantity
+	}
+	if slave == BroadcastAddress {
+		return nil, ErrBroadcast
+	}
+
+	response, err := c.transact(ctx, slave, requestPDU(function, address, quantity))
+	if err != nil {
+		return nil, err
+	}
+	if len(response) != 2+2*int(quantity) || int(response[1]) != 2*int(quantity) {
+		return nil, ErrInvalidResponse
+	}
+	return decodeRegisters(response[2:]), nil
+}
+
+// writeSingle writes a single coil or register. The response echoes the request.
+func (c *Client) writeSingle(ctx context.Context, slave byte, function byte, address, value uint16) error {
+	pdu := requestPDU(function, address, value)
+
+	response, err := c.transact(ctx, slave, pdu)
+	if err != nil || slave == BroadcastAddress {
+		return err
+	}
+	if !bytes.Equal(response, pdu) {
+		return ErrInvalidResponse
+	}
+	return nil
+}
+
+// writeMultiple writes multiple coils or registers. The response echoes address and quantity.
+func (c *Client) writeMultiple(ctx context.Context, slave byte, function byte, address, quantity uint16, data []byte) error {
+	pdu := append(requestPDU(function, address, quantity), byte(len(data)))
+	pdu = append(pdu, data...)
+
+	response, err := c.transact(ctx, slave, pdu)
+	if err != nil || slave == BroadcastAddress {
+		return err
+	}
+	if !bytes.Equal(response, pdu[:5]) {
+		return ErrInvalidResponse
+	}
+	return nil
+}
+
+// requestPDU returns the pdu of function with the two 16 bit fields used by most requests.
+func requestPDU(function byte, address, value uint16) []byte {
+	pdu := make([]byte, 0, 5)
+	pdu = append(pdu, function)
+	pdu = binary.BigEndian.AppendUint16(pdu, address)
+	return binary.BigEndian.AppendUint16(pdu, value)
+}
+
+// transact sends pdu to slave and returns the pdu of the response.
+// Frames of other slaves or function codes are discarded, e.g. if another master shares the bus.
+// Broadcast requests return without waiting for a response.
+func (c *Client) transact(ctx context.Context, slave byte, pdu []byte) ([]byte, error) {
+	var match func(framereader.Frame) bool
+	if slave != BroadcastAddress {
+		match = func(f framereader.Frame) bool {
+			address, response, ok := decodeADU(f.Data)
+			// frames with an invalid CRC are returned as error
+			return !ok || address == slave && response[0]&^exceptionFlag == pdu[0]
+		}
+	}
+
+	f, err := c.transactor.Transact(ctx, encodeADU(slave, pdu), match)
+	if err != nil || slave == BroadcastAddress {
+		return nil, err
+	}
+
+	_, response, ok := decodeADU(f.Data)
+	if !ok {
+		return nil, framereader.ErrChecksum
+	}
+	if response[0]&exceptionFlag != 0 {
+		if len(response) != 2 {
+			return nil, ErrInvalidResponse
+		}
+		return nil, &ExceptionError{Slave: slave, FunctionCode: pdu[0], Exception: Exception(response[1])}
+	}
+	return response, nil
+}
@@ -0,0 +1,156 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/womat/framereader"
+	"github.com/womat/framereader/framereadertest/transporttest"
+)
+
+var (
+	_ Transport = (*framereader.ReadWriteCloser)(nil)
+	_ Transport = (*framereader.ReadWriter)(nil)
+)
+
+// reply returns a transport, which expects the request pdu and answers with the response pdus of slave 1.
+func reply(t *testing.T, request []byte, responses ...[]byte) *transporttest.Transport {
+	return &transporttest.Transport{Respond: func(adu []byte) [][]byte {
+		if exp := encodeADU(1, request); !bytes.Equal(adu, exp) {
+			t.Errorf("expected request % x, got % x", exp, adu)
+		}
+		var frames [][]byte
+		for _, r := range responses {
+			frames = append(frames, encodeADU(1, r))
+		}
+		return frames
+	}}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	// the examples of the Modbus application protocol specification
+	c := NewClient(reply(t, []byte{0x01, 0x00, 0x13, 0x00, 0x13}, []byte{0x01, 0x03, 0xcd, 0x6b, 0x05}))
+	bits, err := c.ReadCoils(ctx, 1, 0x13, 0x13)
+	exp := []bool{true, false, true, true, false, false, true, true, true, true, false, true, false, true, true, false, true, false, true}
+	if err != nil || !reflect.DeepEqual(bits, exp) {
+		t.Error("ReadCoils: unexpected result: ", bits, err)
+	}
+
+	c = NewClient(reply(t, []byte{0x02, 0x00, 0xc4, 0x00, 0x03}, []byte{0x02, 0x01, 0x05}))
+	bits, err = c.ReadDiscreteInputs(ctx, 1, 0xc4, 3)
+	if err != nil || !reflect.DeepEqual(bits, []bool{true, false, true}) {
+		t.Error("ReadDiscreteInputs: unexpected result: ", bits, err)
+	}
+
+	c = NewClient(reply(t, []byte{0x03, 0x00, 0x6b, 0x00, 0x03}, []byte{0x03, 0x06, 0x02, 0x2b, 0x00, 0x00, 0x00, 0x64}))
+	registers, err := c.ReadHoldingRegisters(ctx, 1, 0x6b, 3)
+	if err != nil || !reflect.DeepEqual(registers, []uint16{555, 0, 100}) {
+		t.Error("ReadHoldingRegisters: unexpected result: ", registers, err)
+	}
+
+	c = NewClient(reply(t, []byte{0x04, 0x00, 0x08, 0x00, 0x01}, []byte{0x04, 0x02, 0x00, 0x0a}))
+	registers, err = c.ReadInputRegisters(ctx, 1, 8, 1)
+	if err != nil || !reflect.DeepEqual(registers, []uint16{10}) {
+		t.Error("ReadInputRegisters: unexpected result: ", registers, err)
+	}
+
+	c = NewClient(reply(t, []byte{0x05, 0x00, 0xac, 0xff, 0x00}, []byte{0x05, 0x00, 0xac, 0xff, 0x00}))
+	if err = c.WriteSingleCoil(ctx, 1, 0xac, true); err != nil {
+		t.Error("WriteSingleCoil: ", err)
+	}
+
+	c = NewClient(reply(t, []byte{0x06, 0x00, 0x01, 0x00, 0x03}, []byte{0x06, 0x00, 0x01, 0x00, 0x03}))
+	if err = c.WriteSingleRegister(ctx, 1, 1, 3); err != nil {
+		t.Error("WriteSingleRegister: ", err)
+	}
+
+	c = NewClient(reply(t, []byte{0x0f, 0x00, 0x13, 0x00, 0x0a, 0x02, 0xcd, 0x01}, []byte{0x0f, 0x00, 0x13, 0x00, 0x0a}))
+	if err = c.WriteMultipleCoils(ctx, 1, 0x13, []bool{true, false, true, true, false, false, true, true, true, false}); err != nil {
+		t.Error("WriteMultipleCoils: ", err)
+	}
+
+	c = NewClient(reply(t, []byte{0x10, 0x00, 0x01, 0x00, 0x02, 0x04, 0x00, 0x0a, 0x01, 0x02}, []byte{0x10, 0x00, 0x01, 0x00, 0x02}))
+	if err = c.WriteMultipleRegisters(ctx, 1, 1, []uint16{0x000a, 0x0102}); err != nil {
+		t.Error("WriteMultipleRegisters: ", err)
+	}
+
+	c = NewClient(reply(t,
+		[]byte{0x17, 0x00, 0x03, 0x00, 0x06, 0x00, 0x0e, 0x00, 0x03, 0x06, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff},
+		[]byte{0x17, 0x0c, 0x00, 0xfe, 0x0a, 0xcd, 0x00, 0x01, 0x00, 0x03, 0x00, 0x0d, 0x00, 0xff}))
+	registers, err = c.ReadWriteMultipleRegisters(ctx, 1, 3, 6, 0x0e, []uint16{0xff, 0xff, 0xff})
+	if err != nil || !reflect.DeepEqual(registers, []uint16{0xfe, 0xacd, 1, 3, 0xd, 0xff}) {
+		t.Error("ReadWriteMultipleRegisters: unexpected result: ", registers, err)
+	}
+}
+
+func TestClientException(t *testing.T) {
+	c := NewClient(reply(t, []byte{0x03, 0x00, 0x6b, 0x00, 0x03}, []byte{0x83, 0x02}))
+
+	_, err := c.ReadHoldingRegisters(context.Background(), 1, 0x6b, 3)
+	var e *ExceptionError
+	if !errors.As(err, &e) || e.Slave != 1 || e.FunctionCode != FuncReadHoldingRegisters || !errors.Is(err, IllegalDataAddress) {
+		t.Error("expected illegal data address exception, got: ", err)
+	}
+}
+
+func TestClientResponseMatching(t *testing.T) {
+	ctx := context.Background()
+	response := []byte{0x03, 0x02, 0x00, 0x2a}
+
+	transport := &transporttest.Transport{Respond: func([]byte) [][]byte {
+		return [][]byte{
+			encodeADU(2, response),                       // other slave
+			encodeADU(1, []byte{0x04, 0x02, 0x00, 0x01}), // other function
+			encodeADU(1, response),
+		}
+	}}
+	registers, err := NewClient(transport).ReadHoldingRegisters(ctx, 1, 0, 1)
+	if err != nil || !reflect.DeepEqual(registers, []uint16{42}) {
+		t.Error("expected unrelated frames to be discarded: ", registers, err)
+	}
+
+	transport = &transporttest.Transport{Respond: func([]byte) [][]byte {
+		adu := encodeADU(1, response)
+		adu[len(adu)-1]++
+		return [][]byte{adu}
+	}}
+	if _, err = NewClient(transport).ReadHoldingRegisters(ctx, 1, 0, 1); err != framereader.ErrChecksum {
+		t.Error("expected checksum error, got: ", err)
+	}
+
+	transport = &transporttest.Transport{Respond: func([]byte) [][]byte {
+		return [][]byte{encodeADU(1, []byte{0x03, 0x04, 0x00, 0x2a, 0x00, 0x2b})}
+	}}
+	if _, err = NewClient(transport).ReadHoldingRegisters(ctx, 1, 0, 1); err != ErrInvalidResponse {
+		t.Error("expected invalid response, got: ", err)
+	}
+
+	transport = &transporttest.Transport{Respond: func([]byte) [][]byte { return nil }}
+	if _, err = NewClient(transport).ReadHoldingRegisters(ctx, 1, 0, 1); err != framereader.ErrTimeout {
+		t.Error("expected timeout, got: ", err)
+	}
+}
+
+func TestClientBroadcast(t *testing.T) {
+	ctx := context.Background()
+	transport := &transporttest.Transport{Respond: func([]byte) [][]byte { return nil }}
+	c := NewClient(transport)
+
+	if err := c.WriteSingleRegister(ctx, BroadcastAddress, 1, 2); err != nil {
+		t.Error("expected broadcast without response, got: ", err)
+	}
+	if len(transport.Written()) != 1 || transport.Written()[0][0] != BroadcastAddress {
+		t.Error("expected broadcast request to be written: ", transport.Written())
+	}
+	if _, err := c.ReadHoldingRegisters(ctx, BroadcastAddress, 0, 1); err != ErrBroadcast {
+		t.Error("expected broadcast error, got: ", err)
+	}
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, MaxReadRegisters+1); err != ErrInvalidQuantity {
+		t.Error("expected invalid quantity, got: ", err)
+	}
+}
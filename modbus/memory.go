@@ -0,0 +1,83 @@
+package modbus
+
+import "sync"
+
+// Memory is a Handler storing the coils and registers of an emulated device in memory.
+// Requests beyond the size of the tables are answered by IllegalDataAddress.
+// The tables may be accessed directly, while the Server isn't running, otherwise Lock must be used.
+type Memory struct {
+	sync.Mutex
+	Coils            []bool
+	DiscreteInputs   []bool
+	HoldingRegisters []uint16
+	InputRegisters   []uint16
+}
+
+// NewMemory creates a register map with the given number of coils, discrete inputs, holding and input registers.
+func NewMemory(coils, discreteInputs, holdingRegisters, inputRegisters int) *Memory {
+	return &Memory{
+		Coils:            make([]bool, coils),
+		DiscreteInputs:   make([]bool, discreteInputs),
+		HoldingRegisters: make([]uint16, holdingRegisters),
+		InputRegisters:   make([]uint16, inputRegisters),
+	}
+}
+
+// ReadCoils implements Handler.
+func (m *Memory) ReadCoils(address, quantity uint16) ([]bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	return read(m.Coils, address, int(quantity))
+}
+
+// ReadDiscreteInputs implements Handler.
+func (m *Memory) ReadDiscreteInputs(address, quantity uint16) ([]bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	return read(m.DiscreteInputs, address, int(quantity))
+}
+
+// ReadHoldingRegisters implements Handler.
+func (m *Memory) ReadHoldingRegisters(address, quantity uint16) ([]uint16, error) {
+	m.Lock()
+	defer m.Unlock()
+	return read(m.HoldingRegisters, address, int(quantity))
+}
+
+// ReadInputRegisters implements Handler.
+func (m *Memory) ReadInputRegisters(address, quantity uint16) ([]uint16, error) {
+	m.Lock()
+	defer m.Unlock()
+	return read(m.InputRegisters, address, int(quantity))
+}
+
+// WriteCoils implements Handler.
+func (m *Memory) WriteCoils(address uint16, values []bool) error {
+	m.Lock()
+	defer m.Unlock()
+	return write(m.Coils, address, values)
+}
+
+// WriteHoldingRegisters implements Handler.
+func (m *Memory) WriteHoldingRegisters(address uint16, values []uint16) error {
+	m.Lock()
+	defer m.Unlock()
+	return write(m.HoldingRegisters, address, values)
+}
+
+// read returns a copy of quantity values of table starting at address.
+func read[T any](table []T, address uint16, quantity int) ([]T, error) {
+	if int(address)+quantity > len(table) {
+		return nil, IllegalDataAddress
+	}
+	return append([]T(nil), table[address:int(address)+quantity]...), nil
+}
+
+// write copies values to table starting at address.
+func write[T any](table []T, address uint16, values []T) error {
+	if int(address)+len(values) > len(table) {
+		return IllegalDataAddress
+	}
+	copy(table[address:], values)
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package modbus implements Modbus RTU on top of the frame readers of package framereader.
+//
+// The frames are separated by the silence of 3.5 characters, so the transport should be
+// configured by framereader.WithModbusRTU.
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/womat/framereader/internal/crc"
+)
+
+// Function codes supported by Client and Server.
+const (
+	FuncReadCoils                  byte = 0x01
+	FuncReadDiscreteInputs         byte = 0x02
+	FuncReadHoldingRegisters       byte = 0x03
+	FuncReadInputRegisters         byte = 0x04
+	FuncWriteSingleCoil            byte = 0x05
+	FuncWriteSingleRegister        byte = 0x06
+	FuncWriteMultipleCoils         byte = 0x0f
+	FuncWriteMultipleRegisters     byte = 0x10
+	FuncReadWriteMultipleRegisters byte = 0x17
+)
+
+// exceptionFlag is set in the function code of an exception response.
+const exceptionFlag = 0x80
+
+// BroadcastAddress is the slave address received by all slaves. Broadcast requests aren't answered.
+const BroadcastAddress byte = 0
+
+// Limits of the quantity of coils and registers per request defined by the Modbus specification.
+const (
+	MaxReadBits       = 2000
+	MaxReadRegisters  = 125
+	MaxWriteBits      = 1968
+	MaxWriteRegisters = 123
+	MaxRWRegisters    = 121 // max write quantity of FuncReadWriteMultipleRegisters
+)
+
+var (
+	// ErrInvalidResponse is returned, if the response doesn't match the request.
+	ErrInvalidResponse = errors.New("modbus: invalid response")
+
+	// ErrInvalidQuantity is returned, if the quantity of a request exceeds the limits of the Modbus specification.
+	ErrInvalidQuantity = errors.New("modbus: invalid quantity")
+
+	// ErrBroadcast is returned, if a read request is sent to the BroadcastAddress.
+	ErrBroadcast = errors.New("modbus: read request to broadcast address")
+)
+
+// Exception is the exception code of an exception response.
+// It can be returned by the handler of a Server and is matched by errors.Is for the errors of a Client.
+type Exception byte
+
+// Exception codes defined by the Modbus specification.
+const (
+	IllegalFunction                    Exception = 0x01
+	IllegalDataAddress                 Exception = 0x02
+	IllegalDataValue                   Exception = 0x03
+	ServerDeviceFailure                Exception = 0x04
+	Acknowledge                        Exception = 0x05
+	ServerDeviceBusy                   Exception = 0x06
+	MemoryParityError                  Exception = 0x08
+	GatewayPathUnavailable             Exception = 0x0a
+	GatewayTargetDeviceFailedToRespond Exception = 0x0b
+)
+
+var exceptionText = map[Exception]string{
+	IllegalFunction:                    "illegal function",
+	IllegalDataAddress:                 "illegal data address",
+	IllegalDataValue:                   "illegal data value",
+	ServerDeviceFailure:                "server device failure",
+	Acknowledge:                        "acknowledge",
+	ServerDeviceBusy:                   "server device busy",
+	MemoryParityError:                  "memory parity error",
+	GatewayPathUnavailable:             "gateway path unavailable",
+	GatewayTargetDeviceFailedToRespond: "gateway target device failed to respond",
+}
+
+func (e Exception) Error() string {
+	if s, ok := exceptionText[e]; ok {
+		return "modbus: " + s
+	}
+	return fmt.Sprintf("modbus: exception %#02x", byte(e))
+}
+
+// ExceptionError is returned by Client, if the slave answers with an exception response.
+type ExceptionError struct {
+	Slave        byte
+	FunctionCode byte
+	Exception    Exception
+}
+
+func (e *ExceptionError) Error() string {
+	return fmt.Sprintf("%v (slave %v, function %#02x)", e.Exception, e.Slave, e.FunctionCode)
+}
+
+// Unwrap returns the exception, so errors.Is(err, modbus.IllegalDataAddress) can be used.
+func (e *ExceptionError) Unwrap() error {
+	return e.Exception
+}
+
+// encodeADU returns the RTU frame of pdu sent to or received from slave.
+func encodeADU(slave byte, pdu []byte) []byte {
+	adu := make([]byte, 0, len(pdu)+3)
+	adu = append(adu, slave)
+	adu = append(adu, pdu...)
+	return binary.LittleEndian.AppendUint16(adu, crc.Modbus(adu))
+}
+
+// decodeADU returns the slave address and pdu of an RTU frame.
+// ok is false, if the frame is too short or the CRC is invalid.
+func decodeADU(adu []byte) (slave byte, pdu []byte, ok bool) {
+	n := len(adu) - 2
+	if n < 2 || crc.Modbus(adu[:n]) != binary.LittleEndian.Uint16(adu[n:]) {
+		return 0, nil, false
+	}
+	return adu[0], adu[1:n], true
+}
+
+// encodeBits packs bits into bytes, the first bit is the lsb of the first byte.
+func encodeBits(bits []bool) []byte {
+	data := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			data[i/8] |= 1 << (i % 8)
+		}
+	}
+	return data
+}
+
+// decodeBits unpacks quantity bits from data, see encodeBits.
+func decodeBits(data []byte, quantity int) []bool {
+	bits := make([]bool, quantity)
+	for i := range bits {
+		bits[i] = data[i/8]&(1<<(i%8)) != 0
+	}
+	return bits
+}
+
+// encodeRegisters returns the big endian representation of registers.
+func encodeRegisters(registers []uint16) []byte {
+	data := make([]byte, 0, 2*len(registers))
+	for _, r := range registers {
+		data = binary.BigEndian.AppendUint16(data, r)
+	}
+	return data
+}
+
+// decodeRegisters returns the registers of big endian data.
+func decodeRegisters(data []byte) []uint16 {
+	registers := make([]uint16, len(data)/2)
+	for i := range registers {
+		registers[i] = binary.BigEndian.Uint16(data[2*i:])
+	}
+	return registers
+}
@@ -0,0 +1,252 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"github.com/womat/framereader"
+)
+
+// Transport receives requests and sends the responses.
+// It's implemented by *framereader.ReadWriteCloser and *framereader.ReadWriter.
+type Transport interface {
+	WriteContext(ctx context.Context, buffer []byte) (int, error)
+	ReadFrameContext(ctx context.Context) (framereader.Frame, error)
+}
+
+// Handler serves the requests of a Server, e.g. the register map of an emulated device.
+// If a method returns an Exception, it's sent as exception response,
+// other errors are sent as ServerDeviceFailure.
+type Handler interface {
+	ReadCoils(address, quantity uint16) ([]bool, error)
+	ReadDiscreteInputs(address, quantity uint16) ([]bool, error)
+	ReadHoldingRegisters(address, quantity uint16) ([]uint16, error)
+	ReadInputRegisters(address, quantity uint16) ([]uint16, error)
+	WriteCoils(address uint16, values []bool) error
+	WriteHoldingRegisters(address uint16, values []uint16) error
+}
+
+// Server is a Modbus RTU slave. It answers the requests to its slave address received via the transport.
+type Server struct {
+	transport Transport
+	slave     byte
+	handler   Handler
+}
+
+// NewServer creates a server for slave, which passes the requests received via transport to handler.
+// Broadcast requests are passed to handler, but not answered.
+func NewServer(transport Transport, slave byte, handler Handler) *Server {
+	return &Server{transport: transport, slave: slave, handler: handler}
+}
+
+// Serve answers requests, until ctx is done or the transport fails.
+// Timeouts, invalid frames, e.g. with an invalid CRC or exceeding the max frame size,
+// and requests to other slaves are ignored.
+func (s *Server) Serve(ctx context.Context) error {
+	for {
+		f, err := s.transport.ReadFrameContext(ctx)
+		switch {
+		case err == nil:
+		case invalidFrame(err):
+			continue
+		default:
+			return err
+		}
+
+		slave, request, ok := decodeADU(f.Data)
+		if !ok || slave != s.slave && slave != BroadcastAddress {
+			continue
+		}
+
+		response := s.handle(request)
+		if slave == BroadcastAddress {
+			continue
+		}
+		if _, err := s.transport.WriteContext(ctx, encodeADU(s.slave, response)); err != nil {
+			return err
+		}
+	}
+}
+
+// invalidFrame reports whether err is a timeout or an error of a single frame, which doesn't affect the transport.
+func invalidFrame(err error) bool {
+	for _, e := range []error{
+		framereader.ErrTimeout,
+		framereader.ErrChecksum,
+		framereader.ErrFrameTooLarge,
+		framereader.ErrEncoding,
+		framereader.ErrInvalidLength,
+	} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle returns the response pdu of the request pdu.
+func (s *Server) handle(request []byte) []byte {
+	response, err := s.dispatch(request)
+	if err != nil {
+		var e Exception
+		if !errors.As(err, &e) {
+			e = ServerDeviceFailure
+		}
+		return []byte{request[0] | exceptionFlag, byte(e)}
+	}
+	return response
+}
+
+// dispatch validates the request pdu and passes it to the handler.
+func (s *Server) dispatch(request []byte) ([]byte, error) {
+	function, data := request[0], request[1:]
+
+	switch function {
+	case FuncReadCoils, FuncReadDiscreteInputs:
+		address, quantity, ok := decodeRange(data, MaxReadBits)
+		if !ok {
+			return nil, IllegalDataValue
+		}
+		read := s.handler.ReadCoils
+		if function == FuncReadDiscreteInputs {
+			read = s.handler.ReadDiscreteInputs
+		}
+		bits, err := read(address, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if len(bits) != int(quantity) {
+			return nil, ServerDeviceFailure
+		}
+		return appendData([]byte{function}, encodeBits(bits)), nil
+
+	case FuncReadHoldingRegisters, FuncReadInputRegisters:
+		address, quantity, ok := decodeRange(data, MaxReadRegisters)
+		if !ok {
+			return nil, IllegalDataValue
+		}
+		read := s.handler.ReadHoldingRegisters
+		if function == FuncReadInputRegisters {
+			read = s.handler.ReadInputRegisters
+		}
+		registers, err := read(address, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if len(registers) != int(quantity) {
+			return nil, ServerDeviceFailure
+		}
+		return appendData([]byte{function}, encodeRegisters(registers)), nil
+
+	case FuncWriteSingleCoil:
+		if len(data) != 4 {
+			return nil, IllegalDataValue
+		}
+		var value bool
+		switch binary.BigEndian.Uint16(data[2:]) {
+		case 0xff00:
+			value = true
+		case 0x0000:
+		default:
+			return nil, IllegalDataValue
+		}
+		if err := s.handler.WriteCoils(binary.BigEndian.Uint16(data), []bool{value}); err != nil {
+			return nil, err
+		}
+		return request, nil
+
+	case FuncWriteSingleRegister:
+		if len(data) != 4 {
+			return nil, IllegalDataValue
+		}
+		if err := s.handler.WriteHoldingRegisters(binary.BigEndian.Uint16(data), decodeRegisters(data[2:])); err != nil {
+			return nil, err
+		}
+		return request, nil
+
+	case FuncWriteMultipleCoils:
+		address, quantity, values, ok := decodeWrite(data, MaxWriteBits, bitsSize)
+		if !ok {
+			return nil, IllegalDataValue
+		}
+		if err := s.handler.WriteCoils(address, decodeBits(values, int(quantity))); err != nil {
+			return nil, err
+		}
+		return request[:5], nil
+
+	case FuncWriteMultipleRegisters:
+		address, _, values, ok := decodeWrite(data, MaxWriteRegisters, registersSize)
+		if !ok {
+			return nil, IllegalDataValue
+		}
+		if err := s.handler.WriteHoldingRegisters(address, decodeRegisters(values)); err != nil {
+			return nil, err
+		}
+		return request[:5], nil
+
+	case FuncReadWriteMultipleRegisters:
+		if len(data) < 9 {
+			return nil, IllegalDataValue
+		}
+		readAddress, readQuantity, ok := decodeRange(data[:4], MaxReadRegisters)
+		if !ok {
+			return nil, IllegalDataValue
+		}
+		writeAddress, _, values, ok := decodeWrite(data[4:], MaxRWRegisters, registersSize)
+		if !ok {
+			return nil, IllegalDataValue
+		}
+		if err := s.handler.WriteHoldingRegisters(writeAddress, decodeRegisters(values)); err != nil {
+			return nil, err
+		}
+		registers, err := s.handler.ReadHoldingRegisters(readAddress, readQuantity)
+		if err != nil {
+			return nil, err
+		}
+		if len(registers) != int(readQuantity) {
+			return nil, ServerDeviceFailure
+		}
+		return appendData([]byte{function}, encodeRegisters(registers)), nil
+
+	default:
+		return nil, IllegalFunction
+	}
+}
+
+// decodeRange returns address and quantity of a read request. ok is false, if the quantity exceeds max.
+func decodeRange(data []byte, max int) (address, quantity uint16, ok bool) {
+	if len(data) != 4 {
+		return 0, 0, false
+	}
+	address, quantity = binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	return address, quantity, quantity >= 1 && int(quantity) <= max
+}
+
+// decodeWrite returns address, quantity and values of a write multiple request.
+// ok is false, if the quantity exceeds max or the byte count doesn't match the size of quantity values.
+func decodeWrite(data []byte, max int, size func(quantity int) int) (address, quantity uint16, values []byte, ok bool) {
+	if len(data) < 5 {
+		return 0, 0, nil, false
+	}
+	address, quantity, ok = decodeRange(data[:4], max)
+	values = data[5:]
+	n := size(int(quantity))
+	return address, quantity, values, ok && int(data[4]) == n && len(values) == n
+}
+
+// bitsSize returns the number of bytes of quantity coils.
+func bitsSize(quantity int) int {
+	return (quantity + 7) / 8
+}
+
+// registersSize returns the number of bytes of quantity registers.
+func registersSize(quantity int) int {
+	return 2 * quantity
+}
+
+// appendData appends the byte count and data to pdu.
+func appendData(pdu []byte, data []byte) []byte {
+	pdu = append(pdu, byte(len(data)))
+	return append(pdu, data...)
+}
@@ -0,0 +1,165 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader"
+)
+
+// newPair returns a client and a server connected by a pipe. The server emulates slave 1.
+func newPair(t *testing.T, memory *Memory) (*Client, func()) {
+	master, slave := net.Pipe()
+	options := []framereader.Option{
+		framereader.WithModbusRTU(115200, framereader.ChecksumDrop),
+		framereader.WithInterFrameDelay(5 * time.Millisecond),
+		framereader.WithTimeout(200 * time.Millisecond),
+	}
+	client := framereader.New(master, options...).(*framereader.ReadWriteCloser)
+	server := framereader.New(slave, options...).(*framereader.ReadWriteCloser)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- NewServer(server, 1, memory).Serve(ctx)
+	}()
+
+	return NewClient(client), func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Error("expected server to be canceled, got: ", err)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(16, 4, 8, 2)
+	memory.DiscreteInputs[1] = true
+	memory.InputRegisters[1] = 42
+
+	client, stop := newPair(t, memory)
+	defer stop()
+
+	if err := client.WriteMultipleRegisters(ctx, 1, 2, []uint16{1, 2, 3}); err != nil {
+		t.Error("WriteMultipleRegisters: ", err)
+	}
+	if err := client.WriteSingleRegister(ctx, 1, 5, 4); err != nil {
+		t.Error("WriteSingleRegister: ", err)
+	}
+	registers, err := client.ReadHoldingRegisters(ctx, 1, 1, 5)
+	if err != nil || !reflect.DeepEqual(registers, []uint16{0, 1, 2, 3, 4}) {
+		t.Error("ReadHoldingRegisters: unexpected result: ", registers, err)
+	}
+	registers, err = client.ReadWriteMultipleRegisters(ctx, 1, 0, 2, 0, []uint16{7})
+	if err != nil || !reflect.DeepEqual(registers, []uint16{7, 0}) {
+		t.Error("ReadWriteMultipleRegisters: unexpected result: ", registers, err)
+	}
+	registers, err = client.ReadInputRegisters(ctx, 1, 0, 2)
+	if err != nil || !reflect.DeepEqual(registers, []uint16{0, 42}) {
+		t.Error("ReadInputRegisters: unexpected result: ", registers, err)
+	}
+
+	if err = client.WriteMultipleCoils(ctx, 1, 8, []bool{true, false, true}); err != nil {
+		t.Error("WriteMultipleCoils: ", err)
+	}
+	if err = client.WriteSingleCoil(ctx, 1, 9, true); err != nil {
+		t.Error("WriteSingleCoil: ", err)
+	}
+	bits, err := client.ReadCoils(ctx, 1, 7, 4)
+	if err != nil || !reflect.DeepEqual(bits, []bool{false, true, true, true}) {
+		t.Error("ReadCoils: unexpected result: ", bits, err)
+	}
+	bits, err = client.ReadDiscreteInputs(ctx, 1, 0, 4)
+	if err != nil || !reflect.DeepEqual(bits, []bool{false, true, false, false}) {
+		t.Error("ReadDiscreteInputs: unexpected result: ", bits, err)
+	}
+
+	if _, err = client.ReadHoldingRegisters(ctx, 1, 6, 3); !errors.Is(err, IllegalDataAddress) {
+		t.Error("expected illegal data address, got: ", err)
+	}
+	if _, err = client.ReadHoldingRegisters(ctx, 2, 0, 1); err != framereader.ErrTimeout {
+		t.Error("expected request to other slave to time out, got: ", err)
+	}
+
+	if err = client.WriteSingleRegister(ctx, BroadcastAddress, 0, 9); err != nil {
+		t.Error("broadcast: ", err)
+	}
+	// the next request must not be sent within the inter frame delay, because broadcasts aren't answered
+	time.Sleep(20 * time.Millisecond)
+	registers, err = client.ReadHoldingRegisters(ctx, 1, 0, 1)
+	if err != nil || !reflect.DeepEqual(registers, []uint16{9}) {
+		t.Error("expected broadcast to be written: ", registers, err)
+	}
+}
+
+// scriptedTransport returns the frames and errors in turn and records the frames written.
+type scriptedTransport struct {
+	frames  []framereader.Frame
+	errs    []error
+	written [][]byte
+}
+
+func (t *scriptedTransport) ReadFrameContext(context.Context) (framereader.Frame, error) {
+	f, err := t.frames[0], t.errs[0]
+	t.frames, t.errs = t.frames[1:], t.errs[1:]
+	return f, err
+}
+
+func (t *scriptedTransport) WriteContext(_ context.Context, buffer []byte) (int, error) {
+	t.written = append(t.written, buffer)
+	return len(buffer), nil
+}
+
+func TestServerInvalidFrames(t *testing.T) {
+	request := framereader.Frame{Data: encodeADU(1, []byte{FuncReadHoldingRegisters, 0, 0, 0, 1})}
+	transport := &scriptedTransport{
+		frames: []framereader.Frame{{}, {Data: []byte{1, 2}}, {}, {}, request, {}},
+		errs: []error{
+			framereader.ErrTimeout,
+			framereader.ErrFrameTooLarge,
+			framereader.ErrEncoding,
+			framereader.ErrInvalidLength,
+			nil,
+			io.EOF,
+		},
+	}
+
+	if err := NewServer(transport, 1, NewMemory(0, 0, 1, 0)).Serve(context.Background()); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+	if len(transport.written) != 1 {
+		t.Error("expected a single response: ", transport.written)
+	}
+}
+
+func TestServerHandle(t *testing.T) {
+	s := NewServer(nil, 1, NewMemory(8, 0, 8, 0))
+
+	tests := []struct {
+		name     string
+		request  []byte
+		response []byte
+	}{
+		{"illegal function", []byte{0x2b, 0x0e}, []byte{0xab, byte(IllegalFunction)}},
+		{"quantity 0", []byte{0x03, 0x00, 0x00, 0x00, 0x00}, []byte{0x83, byte(IllegalDataValue)}},
+		{"short request", []byte{0x0f, 0x00}, []byte{0x8f, byte(IllegalDataValue)}},
+		{"byte count", []byte{0x10, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00}, []byte{0x90, byte(IllegalDataValue)}},
+		{"coil value", []byte{0x05, 0x00, 0x00, 0x12, 0x34}, []byte{0x85, byte(IllegalDataValue)}},
+		{"address", []byte{0x01, 0x00, 0x07, 0x00, 0x02}, []byte{0x81, byte(IllegalDataAddress)}},
+	}
+
+	for _, tt := range tests {
+		if response := s.handle(tt.request); !bytes.Equal(response, tt.response) {
+			t.Errorf("%v: expected % x, got % x", tt.name, tt.response, response)
+		}
+	}
+}
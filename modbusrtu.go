@@ -0,0 +1,69 @@
+package framereader
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/womat/framereader/internal/crc"
+)
+
+// ModbusRTUInterFrameDelay returns the silence of 3.5 characters, which ends a Modbus RTU frame at baudrate.
+// A character has 11 bits (start bit, 8 data bits, parity or second stop bit, stop bit).
+// Above 19200 baud, the fixed delay of 1.75 ms is used as recommended by the Modbus specification.
+func ModbusRTUInterFrameDelay(baudrate int) time.Duration {
+	if baudrate > 19200 || baudrate <= 0 {
+		return 1750 * time.Microsecond
+	}
+	return time.Duration(int64(time.Second) * 11 * 35 / 10 / int64(baudrate))
+}
+
+// ChecksumPolicy defines how frames with an invalid checksum are handled.
+type ChecksumPolicy int
+
+const (
+	// ChecksumDrop drops frames with an invalid checksum. This is the default.
+	ChecksumDrop ChecksumPolicy = iota
+
+	// ChecksumError returns frames with an invalid checksum together with ErrChecksum.
+	ChecksumError
+)
+
+// modbusRTUFramer ends a frame after the silence of 3.5 characters and validates the CRC.
+type modbusRTUFramer struct {
+	policy ChecksumPolicy
+}
+
+// NewModbusRTUFramer creates a framer for Modbus RTU frames, which are separated by the inter frame delay,
+// see ModbusRTUInterFrameDelay. The CRC-16/MODBUS trailer is validated and part of the frame.
+func NewModbusRTUFramer(policy ChecksumPolicy) Framer {
+	return modbusRTUFramer{policy: policy}
+}
+
+// Split returns all buffered data, if the line is idle and the CRC is valid.
+func (f modbusRTUFramer) Split(data []byte, idle bool) (int, []byte, error) {
+	if !idle || len(data) == 0 {
+		return 0, nil, nil
+	}
+	if validModbusRTU(data) {
+		return len(data), data, nil
+	}
+	if f.policy == ChecksumError {
+		return len(data), data, ErrChecksum
+	}
+	return len(data), nil, nil
+}
+
+// validModbusRTU reports whether data has the minimal size of address, function code and CRC and a valid CRC.
+func validModbusRTU(data []byte) bool {
+	n := len(data) - 2
+	return n >= 2 && crc.Modbus(data[:n]) == binary.LittleEndian.Uint16(data[n:])
+}
+
+// WithModbusRTU configures the reader as Modbus RTU transport for baudrate.
+// The inter frame delay is set by ModbusRTUInterFrameDelay and the framer by NewModbusRTUFramer.
+func WithModbusRTU(baudrate int, policy ChecksumPolicy) Option {
+	return func(c *Config) {
+		c.InterFrameDelay = ModbusRTUInterFrameDelay(baudrate)
+		c.Framer = NewModbusRTUFramer(policy)
+	}
+}
@@ -0,0 +1,74 @@
+package framereader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestModbusRTUInterFrameDelay(t *testing.T) {
+	tests := []struct {
+		baudrate int
+		delay    time.Duration
+	}{
+		{9600, 4010416 * time.Nanosecond},
+		{19200, 2005208 * time.Nanosecond},
+		{38400, 1750 * time.Microsecond},
+		{115200, 1750 * time.Microsecond},
+	}
+
+	for _, tt := range tests {
+		if d := ModbusRTUInterFrameDelay(tt.baudrate); d != tt.delay {
+			t.Errorf("%v baud: expected %v, got %v", tt.baudrate, tt.delay, d)
+		}
+	}
+}
+
+func TestModbusRTUFramer(t *testing.T) {
+	valid := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0a, 0xc5, 0xcd}
+	invalid := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0a, 0xc5, 0xce}
+
+	tests := []struct {
+		name    string
+		policy  ChecksumPolicy
+		data    []byte
+		idle    bool
+		advance int
+		frame   []byte
+		err     error
+	}{
+		{"not idle", ChecksumDrop, valid, false, 0, nil, nil},
+		{"valid", ChecksumDrop, valid, true, 8, valid, nil},
+		{"drop", ChecksumDrop, invalid, true, 8, nil, nil},
+		{"error", ChecksumError, invalid, true, 8, invalid, ErrChecksum},
+		{"too short", ChecksumError, []byte{0x01, 0xff}, true, 2, []byte{0x01, 0xff}, ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		advance, frame, err := NewModbusRTUFramer(tt.policy).Split(tt.data, tt.idle)
+		if advance != tt.advance || !bytes.Equal(frame, tt.frame) || err != tt.err {
+			t.Errorf("%v: expected %v, %v, %v, got %v, %v, %v", tt.name, tt.advance, tt.frame, tt.err, advance, frame, err)
+		}
+	}
+}
+
+func TestReaderModbusRTU(t *testing.T) {
+	valid := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0a, 0xc5, 0xcd}
+	invalid := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0a, 0xc5, 0xce}
+
+	r, w := io.Pipe()
+	reader := New(r, WithTimeout(time.Second), WithModbusRTU(9600, ChecksumDrop)).(*ReadCloser)
+	defer reader.Close()
+
+	go func() {
+		w.Write(invalid)
+		time.Sleep(50 * time.Millisecond)
+		w.Write(valid)
+	}()
+
+	f, err := reader.ReadFrame()
+	if err != nil || !bytes.Equal(f.Data, valid) {
+		t.Errorf("expected invalid frame to be dropped, got %v (err: %v)", f.Data, err)
+	}
+}
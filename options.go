@@ -0,0 +1,110 @@
+package framereader
+
+import (
+	"io"
+	"log/slog"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the overall timeout used by New, if no timeout is configured.
+	DefaultTimeout = time.Second
+
+	// DefaultInterFrameDelay is the inter frame delay used by New, if no inter frame delay is configured.
+	DefaultInterFrameDelay = 10 * time.Millisecond
+
+	// DefaultChannelDepth is the number of received frames buffered, until they are read.
+	DefaultChannelDepth = 5
+)
+
+// Option configures the reader created by New.
+type Option func(*Config)
+
+// New creates the richest wrapper supported by rw:
+// *ReadWriteCloser for an io.ReadWriteCloser, *ReadWriter for an io.ReadWriter,
+// *ReadCloser for an io.ReadCloser and *Reader for any other io.Reader.
+//
+// Options not given use DefaultTimeout, DefaultInterFrameDelay, DefaultMaxFrameSize and DefaultChannelDepth.
+func New(rw io.Reader, options ...Option) io.Reader {
+	config := Config{
+		Timeout:         DefaultTimeout,
+		InterFrameDelay: DefaultInterFrameDelay,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+
+	switch rw := rw.(type) {
+	case io.ReadWriteCloser:
+		return NewReadWriteCloserConfig(rw, config)
+	case io.ReadWriter:
+		return NewReadWriterConfig(rw, config)
+	case io.ReadCloser:
+		return NewReadCloserConfig(rw, config)
+	default:
+		return NewReaderConfig(rw, config)
+	}
+}
+
+// WithTimeout sets the overall timeout of Read.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = timeout
+	}
+}
+
+// WithInterFrameDelay sets the delay, after which the line is considered idle.
+func WithInterFrameDelay(interframedelay time.Duration) Option {
+	return func(c *Config) {
+		c.InterFrameDelay = interframedelay
+	}
+}
+
+// WithFramer sets the framer, which splits the received data into frames.
+func WithFramer(framer Framer) Option {
+	return func(c *Config) {
+		c.Framer = framer
+	}
+}
+
+// WithMaxFrameSize sets the max size of a frame.
+func WithMaxFrameSize(size int) Option {
+	return func(c *Config) {
+		c.MaxFrameSize = size
+	}
+}
+
+// WithOverflow sets the policy for frames exceeding the max frame size.
+func WithOverflow(policy OverflowPolicy) Option {
+	return func(c *Config) {
+		c.Overflow = policy
+	}
+}
+
+// WithChannelDepth sets the number of received frames buffered, until they are read.
+func WithChannelDepth(depth int) Option {
+	return func(c *Config) {
+		c.ChannelDepth = depth
+	}
+}
+
+// WithClock sets the source of time for all timeouts and timestamps.
+func WithClock(clock Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// WithLogger sets the logger used for the log messages of the reader.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithLogLevel overrides the level of the logger, see Reader.SetLogLevel.
+func WithLogLevel(level slog.Leveler) Option {
+	return func(c *Config) {
+		c.LogLevel = level
+	}
+}
@@ -0,0 +1,94 @@
+package framereader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+func TestNew(t *testing.T) {
+	pr, _ := io.Pipe()
+	port := framereadertest.NewPort()
+
+	// the readers are closed in reverse order, so the port is closed by the ReadWriteCloser first
+	reader, ok := New(bytes.NewReader(nil)).(*Reader)
+	if !ok {
+		t.Fatal("expected *Reader for io.Reader")
+	}
+	defer reader.Close()
+
+	readCloser, ok := New(pr).(*ReadCloser)
+	if !ok {
+		t.Fatal("expected *ReadCloser for io.ReadCloser")
+	}
+	defer readCloser.Close()
+
+	readWriter, ok := New(struct {
+		io.Reader
+		io.Writer
+	}{port, port}).(*ReadWriter)
+	if !ok {
+		t.Fatal("expected *ReadWriter for io.ReadWriter")
+	}
+	defer readWriter.reader.Close()
+
+	readWriteCloser, ok := New(port).(*ReadWriteCloser)
+	if !ok {
+		t.Fatal("expected *ReadWriteCloser for io.ReadWriteCloser")
+	}
+	defer readWriteCloser.Close()
+}
+
+func TestNewOptions(t *testing.T) {
+	port := framereadertest.NewPort()
+	defer port.Close()
+
+	clock := framereadertest.NewClock(epoch)
+	framer := NewDelimiterFramer('\n')
+	rwc := New(port,
+		WithTimeout(2*time.Second),
+		WithInterFrameDelay(20*time.Millisecond),
+		WithFramer(framer),
+		WithMaxFrameSize(1024),
+		WithOverflow(OverflowSplit),
+		WithChannelDepth(16),
+		WithClock(clock),
+	).(*ReadWriteCloser)
+	defer rwc.Close()
+	reader := rwc.reader
+
+	if reader.timeout != 2*time.Second {
+		t.Error("expected timeout to be 2s: ", reader.timeout)
+	}
+	if reader.interframedelay != 20*time.Millisecond {
+		t.Error("expected inter frame delay to be 20ms: ", reader.interframedelay)
+	}
+	if reader.framer != framer {
+		t.Error("expected delimiter framer: ", reader.framer)
+	}
+	if reader.maxframesize != 1024 || reader.overflow != OverflowSplit {
+		t.Error("expected max frame size 1024 with split policy: ", reader.maxframesize, reader.overflow)
+	}
+	if cap(reader.dataChan) != 16 {
+		t.Error("expected channel depth 16: ", cap(reader.dataChan))
+	}
+	if reader.clock != clock {
+		t.Error("expected fake clock: ", reader.clock)
+	}
+
+	rwc = New(port).(*ReadWriteCloser)
+	defer rwc.Close()
+	reader = rwc.reader
+	if reader.timeout != DefaultTimeout || reader.interframedelay != DefaultInterFrameDelay {
+		t.Error("expected default timeouts: ", reader.timeout, reader.interframedelay)
+	}
+	if reader.maxframesize != DefaultMaxFrameSize || cap(reader.dataChan) != DefaultChannelDepth {
+		t.Error("expected default sizes: ", reader.maxframesize, cap(reader.dataChan))
+	}
+	if _, ok := reader.clock.(realClock); !ok {
+		t.Error("expected real clock: ", reader.clock)
+	}
+}
@@ -1,7 +1,9 @@
 package framereader
 
 import (
+	"context"
 	"io"
+	"log/slog"
 	"time"
 )
 
@@ -15,15 +17,20 @@ type ReadCloser struct {
 // NewReadCloser creates a new response reader
 //
 // timeout is used to specify an
-// overall timeout. If this timeout is encountered, io.EOF is returned.
+// overall timeout. If this timeout is encountered, ErrTimeout is returned.
 //
 // chunkTimeout is used to specify the max timeout between chunks of data once
 // the response is started. If a delay of chunkTimeout is encountered, the response
 // is considered finished and the Read returns.
 func NewReadCloser(iorw io.ReadCloser, timeout time.Duration, interframedelay time.Duration) *ReadCloser {
+	return NewReadCloserConfig(iorw, Config{Timeout: timeout, InterFrameDelay: interframedelay})
+}
+
+// NewReadCloserConfig creates a new response reader using config.
+func NewReadCloserConfig(iorw io.ReadCloser, config Config) *ReadCloser {
 	return &ReadCloser{
 		closer: iorw,
-		reader: NewReader(iorw, timeout, interframedelay),
+		reader: NewReaderConfig(iorw, config),
 	}
 }
 
@@ -32,8 +39,40 @@ func (rc *ReadCloser) Read(buffer []byte) (int, error) {
 	return rc.reader.Read(buffer)
 }
 
-// Close is a passthrough call.
+// ReadContext reads the response. If ctx is done before a frame is received, ctx.Err() is returned.
+func (rc *ReadCloser) ReadContext(ctx context.Context, buffer []byte) (int, error) {
+	return rc.reader.ReadContext(ctx, buffer)
+}
+
+// ReadFrame reads the next frame including its timing.
+func (rc *ReadCloser) ReadFrame() (Frame, error) {
+	return rc.reader.ReadFrame()
+}
+
+// ReadFrameContext reads the next frame. If ctx is done before a frame is received, ctx.Err() is returned.
+func (rc *ReadCloser) ReadFrameContext(ctx context.Context) (Frame, error) {
+	return rc.reader.ReadFrameContext(ctx)
+}
+
+// SetReadDeadline sets the deadline for future and pending Read calls.
+func (rc *ReadCloser) SetReadDeadline(t time.Time) error {
+	return rc.reader.SetReadDeadline(t)
+}
+
+// SetDeadline is the same as SetReadDeadline, because a ReadCloser doesn't write.
+func (rc *ReadCloser) SetDeadline(t time.Time) error {
+	return rc.reader.SetReadDeadline(t)
+}
+
+// SetLogLevel overrides the level of the logger, see Reader.SetLogLevel.
+func (rc *ReadCloser) SetLogLevel(level slog.Leveler) {
+	rc.reader.SetLogLevel(level)
+}
+
+// Close closes the underlying io.Closer and waits, until the frame reader is stopped.
 func (rc *ReadCloser) Close() error {
-	rc.reader.closed = true
-	return rc.closer.Close()
+	rc.reader.stop()
+	err := rc.closer.Close()
+	rc.reader.wait()
+	return err
 }
@@ -1,14 +1,22 @@
 package framereader
 
 import (
+	"context"
 	"errors"
 	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // framesize is the max buffer size
 const framesize = 255
 
+// DefaultMaxFrameSize is the max frame size, if no max frame size is configured.
+const DefaultMaxFrameSize = framesize
+
 // Reader is used for prompt/response communication protocols where a prompt
 // is sent, and some time later a response is received. Typically, the target takes
 // some amount to formulate the response, and then streams it out. There are two delays:
@@ -16,82 +24,320 @@ const framesize = 255
 // This delay is measured from the last received byte to the first next received byte.
 // The thought is that once you received the 1st byte, all the data should stream out
 // continuously and a short timeout can be used to determine the end of the packet.
+// Protocols with other frame boundaries are supported by configuring a Framer.
 type Reader struct {
 	reader          io.Reader
 	timeout         time.Duration
 	interframedelay time.Duration
-	dataChan        chan []byte
-	closed          bool
+	framer          Framer
+	clock           Clock
+	log             *slog.Logger
+	level           atomic.Pointer[slog.Leveler] // overrides the level of the logger, if not nil
+	maxframesize    int
+	overflow        OverflowPolicy
+	dataChan        chan frame
+	reset           chan struct{} // asks the frame reader to drop the buffered data
+	readDeadline    *deadline
+	done            chan struct{} // closed by Close to stop the frame reader
+	closeOnce       sync.Once
+	wg              sync.WaitGroup // waits for the frame reader goroutines
+	err             error          // read error of reader, valid after dataChan is closed
+}
+
+// Config is used to configure a Reader.
+type Config struct {
+	// Timeout is the overall timeout of Read. If this timeout is encountered, ErrTimeout is returned.
+	Timeout time.Duration
+
+	// InterFrameDelay is the max delay between chunks of data once the response is started.
+	// If a delay of InterFrameDelay is encountered, the line is considered idle.
+	InterFrameDelay time.Duration
+
+	// Framer splits the received data into frames.
+	// If Framer is nil, frames are separated by the inter frame delay.
+	Framer Framer
+
+	// MaxFrameSize is the max size of a frame. If MaxFrameSize is 0, DefaultMaxFrameSize is used.
+	MaxFrameSize int
+
+	// Overflow defines how frames exceeding MaxFrameSize are handled. The default is OverflowTruncate.
+	// The remainder of an oversized frame is found as described by Resyncer.
+	Overflow OverflowPolicy
+
+	// ChannelDepth is the number of received frames buffered, until they are read.
+	// If ChannelDepth is 0, DefaultChannelDepth is used.
+	ChannelDepth int
+
+	// Clock is the source of time for all timeouts and timestamps. If Clock is nil, the real time is used.
+	Clock Clock
+
+	// Logger is used for the log messages of the Reader. Attributes like the port name can be added by Logger.With.
+	// If Logger is nil, the messages are written to the package loggers configured by SetDebug.
+	Logger *slog.Logger
+
+	// LogLevel overrides the level of Logger, if it's not nil. It can be changed at runtime by SetLogLevel.
+	LogLevel slog.Leveler
 }
 
 // NewReader creates a new response reader.
 //
 // timeout is used to specify an
-// overall timeout. If this timeout is encountered, io.EOF is returned.
+// overall timeout. If this timeout is encountered, ErrTimeout is returned.
 //
 // chunkTimeout is used to specify the max timeout between chunks of data once
 // the response is started. If a delay of chunkTimeout is encountered, the response
 // is considered finished and the Read returns.
 func NewReader(reader io.Reader, timeout time.Duration, interframedelay time.Duration) *Reader {
+	return NewReaderConfig(reader, Config{Timeout: timeout, InterFrameDelay: interframedelay})
+}
+
+// NewReaderConfig creates a new response reader using config.
+func NewReaderConfig(reader io.Reader, config Config) *Reader {
+	if config.Framer == nil {
+		config.Framer = NewSilenceFramer()
+	}
+	if config.MaxFrameSize <= 0 {
+		config.MaxFrameSize = DefaultMaxFrameSize
+	}
+	if config.ChannelDepth <= 0 {
+		config.ChannelDepth = DefaultChannelDepth
+	}
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+	if config.Logger == nil {
+		config.Logger = slog.New(legacyHandler{})
+	}
+
 	r := Reader{
 		reader:          reader,
-		timeout:         timeout,
-		interframedelay: interframedelay,
-		dataChan:        make(chan []byte, 5),
+		timeout:         config.Timeout,
+		interframedelay: config.InterFrameDelay,
+		framer:          config.Framer,
+		clock:           config.Clock,
+		maxframesize:    config.MaxFrameSize,
+		overflow:        config.Overflow,
+		dataChan:        make(chan frame, config.ChannelDepth),
+		reset:           make(chan struct{}),
+		readDeadline:    newDeadline(config.Clock),
+		done:            make(chan struct{}),
 	}
+	r.log = slog.New(levelHandler{handler: config.Logger.Handler(), level: &r.level})
+	r.SetLogLevel(config.LogLevel)
+
 	// we have to start a reader goroutine here that lives for the life
-	// of the reader because there is no
-	// way to stop a blocked goroutine
+	// of the reader, it is stopped by Close
+	r.wg.Add(1)
 	go r.framereader()
 
 	return &r
 }
 
 // Read response
+//
+// If no frame is received within the overall timeout, ErrTimeout is returned.
+// If the reader is closed, ErrClosed is returned. If reading from the underlying
+// io.Reader failed, the error of the underlying io.Reader is returned, after all
+// frames received before are read.
 func (r *Reader) Read(buffer []byte) (n int, err error) {
+	return r.ReadContext(context.Background(), buffer)
+}
+
+// ReadContext reads the response like Read. If ctx is done before a frame is received, ctx.Err() is returned.
+func (r *Reader) ReadContext(ctx context.Context, buffer []byte) (n int, err error) {
 	if len(buffer) <= 0 {
 		return 0, errors.New("must supply non-zero length buffer")
 	}
 
-	timeout := time.NewTimer(r.timeout)
+	f, err := r.ReadFrameContext(ctx)
+	return copy(buffer, f.Data), err
+}
+
+// ReadFrame reads the next frame including its timing.
+// The errors are the same as returned by Read.
+func (r *Reader) ReadFrame() (Frame, error) {
+	return r.ReadFrameContext(context.Background())
+}
 
+// ReadFrameContext reads the next frame like ReadFrame. If ctx is done before a frame is received, ctx.Err() is returned.
+func (r *Reader) ReadFrameContext(ctx context.Context) (f Frame, err error) {
+	if err = ctx.Err(); err != nil {
+		return Frame{}, err
+	}
+	if r.isClosed() {
+		return Frame{}, ErrClosed
+	}
+	if isClosed(r.readDeadline.wait()) {
+		return Frame{}, os.ErrDeadlineExceeded
+	}
+
+	timeout := newTimer(r.clock, r.timeout)
+	defer timeout.Stop()
+
+	return r.readFrame(ctx, timeout.C)
+}
+
+// readFrame waits for the next frame, until timeout receives a value.
+func (r *Reader) readFrame(ctx context.Context, timeout <-chan struct{}) (f Frame, err error) {
 	select {
-	case b, ok := <-r.dataChan:
-		n = copy(buffer, b)
+	case rf, ok := <-r.dataChan:
+		f, err = rf.Frame, rf.err
 		if !ok {
-			err = io.EOF
+			err = r.closeErr()
 		}
-	case <-timeout.C:
-		err = io.EOF
+	case <-timeout:
+		err = ErrTimeout
+	case <-r.readDeadline.wait():
+		err = os.ErrDeadlineExceeded
+	case <-ctx.Done():
+		err = ctx.Err()
 	}
 
 	return
 }
 
+// SetReadDeadline sets the deadline for future and pending Read calls.
+// If the deadline is exceeded, Read returns os.ErrDeadlineExceeded.
+// A zero value for t means Read will not time out before the overall timeout.
+func (r *Reader) SetReadDeadline(t time.Time) error {
+	r.readDeadline.set(t)
+	return nil
+}
+
+// SetDeadline is the same as SetReadDeadline, because a Reader doesn't write.
+func (r *Reader) SetDeadline(t time.Time) error {
+	return r.SetReadDeadline(t)
+}
+
+// SetLogLevel overrides the level of the logger of the reader, e.g. LevelTrace enables
+// the hex dumps of all frames. A nil level restores the level of the logger.
+// SetLogLevel is safe to call concurrently with Read.
+func (r *Reader) SetLogLevel(level slog.Leveler) {
+	if level == nil {
+		r.level.Store(nil)
+		return
+	}
+	r.level.Store(&level)
+}
+
 // Flush is used to flush any input data
 func (r *Reader) Flush() (n int, err error) {
+	return r.flush(context.Background(), nil, nil)
+}
+
+// flush drops all received frames, until the line is idle, ctx is done, abort is closed or timeout receives a value.
+// If the line is idle, the data buffered by the frame reader, e.g. a partial frame, is dropped as well,
+// so the next frame starts with the data received after flush.
+func (r *Reader) flush(ctx context.Context, abort, timeout <-chan struct{}) (n int, err error) {
 	frames := 0
-	timeout := time.NewTimer(r.interframedelay)
+	idle := newTimer(r.clock, r.interframedelay)
+	defer func() {
+		idle.Stop()
+	}()
 
 	defer func() {
-		debuglog.Printf("drop %v frames (%v bytes)\n", frames, n)
+		r.log.Debug("drop frames", "direction", "rx", "frames", frames, "bytes", n)
 	}()
 
+	var reset chan<- struct{} // set, when the line is idle
 	for {
 		select {
 		case newData, ok := <-r.dataChan:
-			n += len(newData)
-			tracelog.Printf("drop frame with %v bytes\n", len(newData))
+			n += len(newData.Data)
+			r.log.Log(ctx, LevelTrace, "drop frame", "direction", "rx", "data", hexdump(newData.Data))
 
 			if !ok {
-				return n, io.EOF
+				return n, r.closeErr()
 			}
 
 			frames++
-			timeout.Reset(r.interframedelay)
+			if reset == nil {
+				idle.Stop()
+				idle = newTimer(r.clock, r.interframedelay)
+			}
+
+		case <-idle.C:
+			reset = r.reset
+
+		case reset <- struct{}{}:
+			// the frames sent before the reset are already buffered by dataChan
+			for {
+				select {
+				case newData, ok := <-r.dataChan:
+					if !ok {
+						return n, r.closeErr()
+					}
+					n += len(newData.Data)
+					frames++
+					r.log.Log(ctx, LevelTrace, "drop frame", "direction", "rx", "data", hexdump(newData.Data))
+				default:
+					return n, nil
+				}
+			}
+
+		case <-abort:
+			return n, os.ErrDeadlineExceeded
 
-		case <-timeout.C:
-			return n, nil
+		case <-timeout:
+			return n, ErrTimeout
+
+		case <-ctx.Done():
+			return n, ctx.Err()
 		}
 	}
 }
+
+// closeErr returns the reason, why the frame reader has been stopped. It must be called after dataChan is closed.
+func (r *Reader) closeErr() error {
+	if r.isClosed() {
+		return ErrClosed
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return io.EOF
+}
+
+// Close stops the frame reader and waits, until its goroutines are finished.
+// Pending and future Read calls return ErrClosed.
+//
+// A Read blocked in the underlying io.Reader is interrupted by a read deadline,
+// if the underlying io.Reader implements SetReadDeadline, e.g. net.Conn and os.File.
+// The read deadline is reset to none afterwards, so a read deadline set on the underlying io.Reader
+// by the caller is lost. Otherwise Close waits, until the blocked Read returns, so the caller
+// has to make it return, e.g. by closing the underlying io.Reader before, or use ReadCloser.
+// The underlying io.Reader isn't closed.
+func (r *Reader) Close() error {
+	r.stop()
+	r.wait()
+	if rd, ok := r.reader.(readDeadliner); ok {
+		rd.SetReadDeadline(time.Time{})
+	}
+	return nil
+}
+
+// readDeadliner is implemented by readers supporting read deadlines, e.g. net.Conn and os.File.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// stop signals the frame reader goroutines to stop and interrupts a blocked read of the underlying reader.
+func (r *Reader) stop() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		if rd, ok := r.reader.(readDeadliner); ok {
+			// a fixed time in the past, because the clock of the reader may be a fake clock
+			rd.SetReadDeadline(time.Unix(1, 0))
+		}
+	})
+}
+
+// wait waits, until the frame reader goroutines are finished.
+func (r *Reader) wait() {
+	r.wg.Wait()
+}
+
+// isClosed reports whether Close has been called.
+func (r *Reader) isClosed() bool {
+	return isClosed(r.done)
+}
@@ -1,35 +1,48 @@
 package framereader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/womat/framereader/framereadertest"
 )
 
-type dataSource struct {
-	count int
+// TestMain sets the debug output once, because SetDebug must not be called while readers are running.
+func TestMain(m *testing.M) {
+	SetDebug(os.Stderr, Standard|Debug)
+	os.Exit(m.Run())
 }
 
-func (ds *dataSource) Read(data []byte) (int, error) {
-	ds.count++
-	switch ds.count {
-	case 1:
-		time.Sleep(100 * time.Millisecond)
-		data[0] = 0
-		return 1, nil
-	case 2, 3, 4, 5, 6, 7, 8, 9, 10:
-		time.Sleep(5 * time.Millisecond)
-		data[0] = 1
-		return 1, nil
-	default:
-		time.Sleep(1000 * time.Hour)
+// epoch is the start time of the fake clocks used in the tests.
+var epoch = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sendChunk passes chunk to the frame reader and waits, until the frame reader waits for the next chunk.
+func sendChunk(clock *framereadertest.Clock, port *framereadertest.Port, interframedelay time.Duration, chunk []byte) {
+	port.Send(chunk)
+	clock.BlockUntilTimer(interframedelay)
+}
+
+// sendFrame sends a frame of 1 byte followed by 9 bytes with an inter character delay of 5ms.
+// The line is idle for idle before the frame, idle must be 0 or exceed the inter frame delay of 10ms.
+func sendFrame(clock *framereadertest.Clock, port *framereadertest.Port, idle time.Duration, first, next byte) {
+	if idle > 0 {
+		clock.Advance(idle)
+		clock.BlockUntilTimer(10 * time.Millisecond) // the frame reader rearms the inter frame delay after idle
 	}
+	sendChunk(clock, port, 10*time.Millisecond, []byte{first})
 
-	return 0, nil
+	for i := 0; i < 9; i++ {
+		clock.Advance(5 * time.Millisecond)
+		sendChunk(clock, port, 10*time.Millisecond, []byte{next})
+	}
 }
 
 // the below test illustrates out the goroutine in the reader will close if you close
@@ -57,27 +70,24 @@ func TestReadCloser(t *testing.T) {
 		fmt.Println("reader created")
 
 		fmt.Println("read thread")
-		closed := false
+		closing := false
 		for {
-			if closed {
-				break
-			}
 			rdata := make([]byte, 128)
 			c, err := reader.Read(rdata)
-			if err == io.EOF {
-				fmt.Println("Reader returned EOF, exiting read routine")
+			if err == ErrClosed || err == io.EOF {
+				fmt.Println("Reader returned ", err, ", exiting read routine")
 				break
 			}
 			if err != nil {
 				t.Error("Read error: ", err)
 			}
 			fmt.Println("read count: ", c)
-			if !closed {
+			if !closing {
+				closing = true
 				go func() {
 					time.Sleep(20 * time.Millisecond)
 					fmt.Println("closing read file")
 					reader.Close()
-					closed = true
 				}()
 			}
 		}
@@ -200,22 +210,28 @@ func TestResponseReaderSerialPortClose(t *testing.T) {
 */
 
 func TestReader(t *testing.T) {
-	source := &dataSource{}
-	reader := NewReader(source, time.Second, time.Millisecond*10)
-	SetDebug(os.Stderr, Standard|Debug)
+	clock := framereadertest.NewClock(epoch)
+	port := framereadertest.NewPort()
+	reader := NewReaderConfig(port, Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Clock: clock})
+	defer reader.Close()
+	defer port.Close()
+
+	clock.BlockUntilTimer(10 * time.Millisecond)
+	start := clock.Now()
+	sendFrame(clock, port, 100*time.Millisecond, 0, 1)
+	clock.Advance(10 * time.Millisecond)
 
-	start := time.Now()
 	data := make([]byte, 100)
 	count, err := reader.Read(data)
 
-	dur := time.Since(start)
+	dur := clock.Now().Sub(start)
 
 	if err != nil {
 		t.Error("read failed: ", err)
 	}
 
-	if dur < 100*time.Millisecond || dur > 200*time.Millisecond {
-		t.Error("expected dur to be around 150ms: ", dur)
+	if dur != 155*time.Millisecond {
+		t.Error("expected dur to be 155ms: ", dur)
 	}
 
 	if count != 10 {
@@ -232,39 +248,41 @@ func TestReader(t *testing.T) {
 	}
 }
 
-type dataSourceTimeout struct {
-}
-
-func (ds *dataSourceTimeout) Read(data []byte) (int, error) {
-	time.Sleep(1000 * time.Hour)
-
-	return 0, nil
+// readAsync reads from reader in a goroutine. The result is passed to the returned channel.
+func readAsync(reader io.Reader) chan []interface{} {
+	result := make(chan []interface{}, 1)
+	go func() {
+		data := make([]byte, 100)
+		count, err := reader.Read(data)
+		result <- []interface{}{data[:count], err}
+	}()
+	return result
 }
 
 func TestResponseReaderTimeout(t *testing.T) {
-	source := &dataSourceTimeout{}
-	reader := NewReader(source, time.Second, time.Millisecond*10)
-
-	start := time.Now()
-	data := make([]byte, 100)
-	count, err := reader.Read(data)
-
-	dur := time.Since(start)
-
-	if err != io.EOF {
-		t.Error("expected timeout error, got: ", err)
+	clock := framereadertest.NewClock(epoch)
+	port := framereadertest.NewPort()
+	reader := NewReaderConfig(port, Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Clock: clock})
+	defer reader.Close()
+	defer port.Close()
+
+	start := clock.Now()
+	result := readAsync(reader)
+	clock.BlockUntilTimer(time.Second)
+	clock.Advance(time.Second)
+	r := <-result
+
+	dur := clock.Now().Sub(start)
+
+	if r[1] != ErrTimeout {
+		t.Error("expected timeout error, got: ", r[1])
 	}
 
-	if dur < 900*time.Millisecond || dur > 1100*time.Millisecond {
-		t.Error("expected dur to be around 1s: ", dur)
+	if dur != time.Second {
+		t.Error("expected dur to be 1s: ", dur)
 	}
 
-	if count != 0 {
-		t.Error("expected count to be 0: ", count)
-	}
-
-	data = data[0:count]
-
+	data := r[0].([]byte)
 	expData := []byte{}
 
 	if !reflect.DeepEqual(data, expData) {
@@ -273,60 +291,50 @@ func TestResponseReaderTimeout(t *testing.T) {
 	}
 }
 
-type dataSourceWrite struct {
-	count     int
-	writeData []byte
-}
-
-func (ds *dataSourceWrite) Read(data []byte) (int, error) {
-	ds.count++
-	switch ds.count {
-	case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10:
-		time.Sleep(5 * time.Millisecond)
-		data[0] = 1
-		return 1, nil
-	default:
-		time.Sleep(1000 * time.Hour)
-
-	}
-
-	return 0, nil
-}
-
-func (ds *dataSourceWrite) Write(data []byte) (int, error) {
-	ds.writeData = data
-	return len(data), nil
-}
-
 func TestReadWriter(t *testing.T) {
-	source := &dataSourceWrite{}
-	readWriter := NewReadWriter(source, time.Second, time.Millisecond*10)
+	clock := framereadertest.NewClock(epoch)
+	port := framereadertest.NewPort()
+	readWriter := NewReadWriterConfig(port, Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Clock: clock})
+	defer port.Close()
 
-	writeData := []byte{1, 2}
-	time.Sleep(100 * time.Millisecond)
+	clock.BlockUntilTimer(10 * time.Millisecond)
+	sendFrame(clock, port, 0, 1, 1)
+	clock.Advance(10 * time.Millisecond)
+	clock.BlockUntilTimer(10 * time.Millisecond)
 
-	readWriter.Write(writeData)
+	// the frame received before is dropped by Write
+	writeData := []byte{1, 2}
+	written := make(chan error)
+	go func() {
+		_, err := readWriter.Write(writeData)
+		written <- err
+	}()
+
+	clock.BlockUntilTimer(10 * time.Millisecond) // initial flush timeout
+	clock.BlockUntilTimer(10 * time.Millisecond) // flush timeout after the frame is dropped
+	clock.Advance(10 * time.Millisecond)
+
+	if err := <-written; err != nil {
+		t.Error("write failed: ", err)
+	}
 
-	start := time.Now()
-	data := make([]byte, 100)
-	count, err := readWriter.Read(data)
+	start := clock.Now()
+	result := readAsync(readWriter)
+	clock.BlockUntilTimer(time.Second)
+	clock.Advance(time.Second)
+	r := <-result
 
-	dur := time.Since(start)
+	dur := clock.Now().Sub(start)
 
-	if err != io.EOF {
-		t.Error("expected timeout error: ", err)
+	if r[1] != ErrTimeout {
+		t.Error("expected timeout error: ", r[1])
 	}
 
-	if dur < 900*time.Millisecond || dur > 1100*time.Millisecond {
-		t.Error("expected dur to be around 1s: ", dur)
+	if dur != time.Second {
+		t.Error("expected dur to be 1s: ", dur)
 	}
 
-	if count != 0 {
-		t.Error("expected count to be 0: ", count)
-	}
-
-	data = data[0:count]
-
+	data := r[0].([]byte)
 	expData := []byte{}
 
 	if !reflect.DeepEqual(data, expData) {
@@ -334,7 +342,7 @@ func TestReadWriter(t *testing.T) {
 		t.Error("got     : ", data)
 	}
 
-	if !reflect.DeepEqual(writeData, source.writeData) {
+	if !reflect.DeepEqual([][]byte{writeData}, port.Written()) {
 		t.Error("write data is not correct")
 	}
 }
@@ -379,11 +387,214 @@ func (ds *dataSourceReadCloser) Close() error {
 }
 
 func TestClose(t *testing.T) {
+	before := frameReaderGoroutines()
 	source := &dataSourceReadCloser{}
 	rc := NewReadCloser(source, time.Second, time.Millisecond*10)
-	SetDebug(os.Stderr, Standard)
 
 	time.Sleep(370 * time.Millisecond)
 	rc.Close()
-	time.Sleep(4 * time.Second)
+	checkGoroutineLeaks(t, before)
+}
+
+func TestReadContext(t *testing.T) {
+	port := framereadertest.NewPort()
+	reader := NewReader(port, time.Second, time.Millisecond*10)
+	defer port.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	count, err := reader.ReadContext(ctx, make([]byte, 100))
+	dur := time.Since(start)
+
+	if err != context.DeadlineExceeded {
+		t.Error("expected context deadline exceeded, got: ", err)
+	}
+
+	if dur > 500*time.Millisecond {
+		t.Error("expected dur to be around 50ms: ", dur)
+	}
+
+	if count != 0 {
+		t.Error("expected count to be 0: ", count)
+	}
+}
+
+func TestSetReadDeadline(t *testing.T) {
+	port := framereadertest.NewPort()
+	reader := NewReader(port, time.Second, time.Millisecond*10)
+	defer port.Close()
+
+	reader.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	start := time.Now()
+	_, err := reader.Read(make([]byte, 100))
+	dur := time.Since(start)
+
+	if err != os.ErrDeadlineExceeded {
+		t.Error("expected deadline exceeded, got: ", err)
+	}
+
+	if dur > 500*time.Millisecond {
+		t.Error("expected dur to be around 50ms: ", dur)
+	}
+
+	if _, err = reader.Read(make([]byte, 100)); err != os.ErrDeadlineExceeded {
+		t.Error("expected deadline exceeded on subsequent read, got: ", err)
+	}
+
+	reader.SetReadDeadline(time.Time{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err = reader.ReadContext(ctx, make([]byte, 100)); err != context.DeadlineExceeded {
+		t.Error("expected deadline to be cleared, got: ", err)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	port := framereadertest.NewPort()
+	readWriter := NewReadWriter(port, time.Second, time.Millisecond*10)
+	defer port.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := readWriter.WriteContext(ctx, []byte{1, 2}); err != context.Canceled {
+		t.Error("expected context canceled, got: ", err)
+	}
+
+	readWriter.SetWriteDeadline(time.Now().Add(-time.Second))
+
+	if _, err := readWriter.Write([]byte{1, 2}); err != os.ErrDeadlineExceeded {
+		t.Error("expected deadline exceeded, got: ", err)
+	}
+
+	if written := port.Written(); written != nil {
+		t.Error("expected no data to be written: ", written)
+	}
+}
+
+func TestWriteContextInterrupt(t *testing.T) {
+	conn, peer := net.Pipe()
+	readWriter := NewReadWriteCloser(conn, time.Second, time.Millisecond*10)
+	defer readWriter.Close()
+	defer peer.Close()
+
+	// the write is blocked, until the peer reads
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := readWriter.WriteContext(ctx, []byte{1}); err != context.Canceled {
+		t.Error("expected blocked write to be canceled, got: ", err)
+	}
+
+	go peer.Read(make([]byte, 10))
+	if _, err := readWriter.Write([]byte{2}); err != nil {
+		t.Error("expected write deadline to be reset after the canceled write, got: ", err)
+	}
+
+	// a write deadline set on the underlying writer isn't changed
+	conn.SetWriteDeadline(time.Now().Add(-time.Second))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := readWriter.WriteContext(ctx, []byte{3}); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Error("expected write deadline of the underlying writer to be kept, got: ", err)
+	}
+}
+
+func TestWriteFlushPartialFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		framer   Framer
+		stale    []byte
+		response []byte
+	}{
+		{"delimiter", &DelimiterFramer{End: []byte{'\n'}, IgnoreIdle: true}, []byte("garbage"), []byte("OK\n")},
+		{"length prefix", NewLengthPrefixFramer(1), []byte{5, 1}, []byte{2, 0xaa, 0xbb}},
+	}
+
+	for _, tt := range tests {
+		port := framereadertest.NewPort()
+		readWriter := NewReadWriteCloserConfig(port, Config{Timeout: time.Second, InterFrameDelay: 5 * time.Millisecond, Framer: tt.framer})
+
+		// the partial frame buffered by the frame reader is dropped by Write
+		port.Send(tt.stale)
+		if _, err := readWriter.Write([]byte{1}); err != nil {
+			t.Errorf("%v: write failed: %v", tt.name, err)
+		}
+		port.Send(tt.response)
+
+		f, err := readWriter.ReadFrame()
+		if err != nil || !reflect.DeepEqual(f.Data, tt.response) {
+			t.Errorf("%v: expected %q, got %q (err: %v)", tt.name, tt.response, f.Data, err)
+		}
+		port.Close()
+		readWriter.Close()
+	}
+}
+
+func TestErrTimeout(t *testing.T) {
+	if !errors.Is(ErrTimeout, os.ErrDeadlineExceeded) {
+		t.Error("expected ErrTimeout to match os.ErrDeadlineExceeded")
+	}
+
+	var netErr net.Error
+	if !errors.As(ErrTimeout, &netErr) || !netErr.Timeout() {
+		t.Error("expected ErrTimeout to be a net.Error timeout")
+	}
+}
+
+type dataSourceError struct {
+	count int
+	err   error
+}
+
+func (ds *dataSourceError) Read(data []byte) (int, error) {
+	ds.count++
+	switch ds.count {
+	case 1:
+		data[0] = 1
+		return 1, nil
+	case 2:
+		time.Sleep(5 * time.Millisecond)
+		return 0, &timeoutSourceError{}
+	case 3:
+		data[0] = 2
+		return 1, ds.err
+	default:
+		time.Sleep(1000 * time.Hour)
+	}
+
+	return 0, nil
+}
+
+type timeoutSourceError struct{}
+
+func (timeoutSourceError) Error() string { return "source timeout" }
+func (timeoutSourceError) Timeout() bool { return true }
+
+func TestReadError(t *testing.T) {
+	source := &dataSourceError{err: errors.New("port gone")}
+	reader := NewReader(source, time.Second, time.Millisecond*50)
+
+	data := make([]byte, 100)
+	count, err := reader.Read(data)
+
+	if err != nil {
+		t.Error("read failed: ", err)
+	}
+
+	if expData := []byte{1, 2}; !reflect.DeepEqual(data[:count], expData) {
+		t.Error("expected: ", expData)
+		t.Error("got     : ", data[:count])
+	}
+
+	if _, err = reader.Read(data); err != source.err {
+		t.Error("expected read error of source, got: ", err)
+	}
+
+	if _, err = reader.Read(data); err != source.err {
+		t.Error("expected read error of source on subsequent read, got: ", err)
+	}
 }
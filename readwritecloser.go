@@ -1,31 +1,43 @@
 package framereader
 
 import (
+	"context"
 	"io"
+	"log/slog"
+	"sync"
 	"time"
 )
 
 // ReadWriteCloser is a convenience type that implements io.ReadWriteCloser.
 // Write calls flush reader before writing the prompt.
 type ReadWriteCloser struct {
-	reader *Reader
-	writer io.Writer
-	closer io.Closer
+	reader        *Reader
+	writer        io.Writer
+	closer        io.Closer
+	writeDeadline *deadline
+	transaction   sync.Mutex // serializes Transact
 }
 
 // NewReadWriteCloser creates a new response reader
 //
 // timeout is used to specify an
-// overall timeout. If this timeout is encountered, io.EOF is returned.
+// overall timeout. If this timeout is encountered, ErrTimeout is returned.
 //
 // chunkTimeout is used to specify the max timeout between chunks of data once
 // the response is started. If a delay of chunkTimeout is encountered, the response
 // is considered finished and the Read returns.
 func NewReadWriteCloser(iorw io.ReadWriteCloser, timeout time.Duration, interframedelay time.Duration) *ReadWriteCloser {
+	return NewReadWriteCloserConfig(iorw, Config{Timeout: timeout, InterFrameDelay: interframedelay})
+}
+
+// NewReadWriteCloserConfig creates a new response reader using config.
+func NewReadWriteCloserConfig(iorw io.ReadWriteCloser, config Config) *ReadWriteCloser {
+	reader := NewReaderConfig(iorw, config)
 	return &ReadWriteCloser{
-		closer: iorw,
-		writer: iorw,
-		reader: NewReader(iorw, timeout, interframedelay),
+		closer:        iorw,
+		writer:        iorw,
+		reader:        reader,
+		writeDeadline: newDeadline(reader.clock),
 	}
 }
 
@@ -34,18 +46,71 @@ func (rwc *ReadWriteCloser) Read(buffer []byte) (int, error) {
 	return rwc.reader.Read(buffer)
 }
 
+// ReadContext reads the response. If ctx is done before a frame is received, ctx.Err() is returned.
+func (rwc *ReadWriteCloser) ReadContext(ctx context.Context, buffer []byte) (int, error) {
+	return rwc.reader.ReadContext(ctx, buffer)
+}
+
 // Write flushes all data from reader, and then passes through write call.
 func (rwc *ReadWriteCloser) Write(buffer []byte) (int, error) {
-	n, err := rwc.reader.Flush()
-	if err != nil {
-		return n, err
-	}
+	return rwc.WriteContext(context.Background(), buffer)
+}
+
+// WriteContext writes like Write. If ctx is done before the data is written, ctx.Err() is returned.
+// A write blocked in the underlying writer is only interrupted, if the writer supports write deadlines.
+func (rwc *ReadWriteCloser) WriteContext(ctx context.Context, buffer []byte) (int, error) {
+	return writeContext(ctx, rwc.reader, rwc.writer, rwc.writeDeadline, nil, buffer)
+}
+
+// Transact flushes all data from reader, writes request and reads frames, until a frame satisfies match.
+// Unrelated frames are discarded. The overall timeout applies to the whole transaction, if it's exceeded,
+// ErrTimeout is returned. A frame received with an error, e.g. ErrChecksum, is returned with the error.
+// If match is nil, Transact returns after request is written.
+//
+// Concurrent calls of Transact are serialized, but must not be mixed with concurrent calls of Read or Write.
+func (rwc *ReadWriteCloser) Transact(ctx context.Context, request []byte, match func(Frame) bool) (Frame, error) {
+	rwc.transaction.Lock()
+	defer rwc.transaction.Unlock()
+	return transact(ctx, rwc.reader, rwc.writer, rwc.writeDeadline, request, match)
+}
+
+// ReadFrame reads the next frame including its timing.
+func (rwc *ReadWriteCloser) ReadFrame() (Frame, error) {
+	return rwc.reader.ReadFrame()
+}
+
+// ReadFrameContext reads the next frame. If ctx is done before a frame is received, ctx.Err() is returned.
+func (rwc *ReadWriteCloser) ReadFrameContext(ctx context.Context) (Frame, error) {
+	return rwc.reader.ReadFrameContext(ctx)
+}
+
+// SetReadDeadline sets the deadline for future and pending Read calls.
+func (rwc *ReadWriteCloser) SetReadDeadline(t time.Time) error {
+	return rwc.reader.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future Write calls.
+// If the deadline is exceeded, Write returns os.ErrDeadlineExceeded.
+func (rwc *ReadWriteCloser) SetWriteDeadline(t time.Time) error {
+	rwc.writeDeadline.set(t)
+	return nil
+}
+
+// SetDeadline sets the read and write deadlines.
+func (rwc *ReadWriteCloser) SetDeadline(t time.Time) error {
+	rwc.reader.SetReadDeadline(t)
+	return rwc.SetWriteDeadline(t)
+}
 
-	return rwc.writer.Write(buffer)
+// SetLogLevel overrides the level of the logger, see Reader.SetLogLevel.
+func (rwc *ReadWriteCloser) SetLogLevel(level slog.Leveler) {
+	rwc.reader.SetLogLevel(level)
 }
 
-// Close is a passthrough call.
+// Close closes the underlying io.Closer and waits, until the frame reader is stopped.
 func (rwc *ReadWriteCloser) Close() error {
-	rwc.reader.closed = true
-	return rwc.closer.Close()
+	rwc.reader.stop()
+	err := rwc.closer.Close()
+	rwc.reader.wait()
+	return err
 }
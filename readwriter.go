@@ -1,22 +1,35 @@
 package framereader
 
 import (
+	"context"
 	"io"
+	"log/slog"
+	"os"
+	"sync"
 	"time"
 )
 
 // ReadWriter is a convenience type that implements io.ReadWriter. Write
 // calls flush reader before writing the prompt.
 type ReadWriter struct {
-	writer io.Writer
-	reader *Reader
+	writer        io.Writer
+	reader        *Reader
+	writeDeadline *deadline
+	transaction   sync.Mutex // serializes Transact
 }
 
 // NewReadWriter creates a new response reader
 func NewReadWriter(iorw io.ReadWriter, timeout time.Duration, interframedelay time.Duration) *ReadWriter {
+	return NewReadWriterConfig(iorw, Config{Timeout: timeout, InterFrameDelay: interframedelay})
+}
+
+// NewReadWriterConfig creates a new response reader using config.
+func NewReadWriterConfig(iorw io.ReadWriter, config Config) *ReadWriter {
+	reader := NewReaderConfig(iorw, config)
 	return &ReadWriter{
-		writer: iorw,
-		reader: NewReader(iorw, timeout, interframedelay),
+		writer:        iorw,
+		reader:        reader,
+		writeDeadline: newDeadline(reader.clock),
 	}
 }
 
@@ -25,12 +38,142 @@ func (rw *ReadWriter) Read(buffer []byte) (int, error) {
 	return rw.reader.Read(buffer)
 }
 
+// ReadContext reads the response. If ctx is done before a frame is received, ctx.Err() is returned.
+func (rw *ReadWriter) ReadContext(ctx context.Context, buffer []byte) (int, error) {
+	return rw.reader.ReadContext(ctx, buffer)
+}
+
 // Write flushes all data from reader, and then passes through write call.
 func (rw *ReadWriter) Write(buffer []byte) (int, error) {
-	n, err := rw.reader.Flush()
+	return rw.WriteContext(context.Background(), buffer)
+}
+
+// WriteContext writes like Write. If ctx is done before the data is written, ctx.Err() is returned.
+// A write blocked in the underlying writer is only interrupted, if the writer supports write deadlines.
+func (rw *ReadWriter) WriteContext(ctx context.Context, buffer []byte) (int, error) {
+	return writeContext(ctx, rw.reader, rw.writer, rw.writeDeadline, nil, buffer)
+}
+
+// Transact flushes all data from reader, writes request and reads frames, until a frame satisfies match.
+// Unrelated frames are discarded. The overall timeout applies to the whole transaction, if it's exceeded,
+// ErrTimeout is returned. A frame received with an error, e.g. ErrChecksum, is returned with the error.
+// If match is nil, Transact returns after request is written.
+//
+// Concurrent calls of Transact are serialized, but must not be mixed with concurrent calls of Read or Write.
+func (rw *ReadWriter) Transact(ctx context.Context, request []byte, match func(Frame) bool) (Frame, error) {
+	rw.transaction.Lock()
+	defer rw.transaction.Unlock()
+	return transact(ctx, rw.reader, rw.writer, rw.writeDeadline, request, match)
+}
+
+// ReadFrame reads the next frame including its timing.
+func (rw *ReadWriter) ReadFrame() (Frame, error) {
+	return rw.reader.ReadFrame()
+}
+
+// ReadFrameContext reads the next frame. If ctx is done before a frame is received, ctx.Err() is returned.
+func (rw *ReadWriter) ReadFrameContext(ctx context.Context) (Frame, error) {
+	return rw.reader.ReadFrameContext(ctx)
+}
+
+// SetReadDeadline sets the deadline for future and pending Read calls.
+func (rw *ReadWriter) SetReadDeadline(t time.Time) error {
+	return rw.reader.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future Write calls.
+// If the deadline is exceeded, Write returns os.ErrDeadlineExceeded.
+func (rw *ReadWriter) SetWriteDeadline(t time.Time) error {
+	rw.writeDeadline.set(t)
+	return nil
+}
+
+// SetDeadline sets the read and write deadlines.
+func (rw *ReadWriter) SetDeadline(t time.Time) error {
+	rw.reader.SetReadDeadline(t)
+	return rw.SetWriteDeadline(t)
+}
+
+// SetLogLevel overrides the level of the logger, see Reader.SetLogLevel.
+func (rw *ReadWriter) SetLogLevel(level slog.Leveler) {
+	rw.reader.SetLogLevel(level)
+}
+
+// writeDeadliner is implemented by writers supporting write deadlines, e.g. net.Conn and os.File.
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// writeContext flushes all data from reader, and then writes buffer to writer,
+// unless ctx is done or the write deadline is exceeded. If timeout receives a value before the flush is finished,
+// ErrTimeout is returned.
+// If writer supports write deadlines, a blocked write is interrupted, when ctx is done or the deadline is exceeded.
+// If the framer of reader implements Encoder, buffer is written as encoded frame.
+func writeContext(ctx context.Context, reader *Reader, writer io.Writer, d *deadline, timeout <-chan struct{}, buffer []byte) (n int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if isClosed(d.wait()) {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	n, err = reader.flush(ctx, d.wait(), timeout)
 	if err != nil {
 		return n, err
 	}
 
-	return rw.writer.Write(buffer)
+	if wd, ok := writer.(writeDeadliner); ok {
+		stop := watchWrite(ctx, wd, d.wait())
+		defer func() {
+			if stop() && err != nil && ctx.Err() != nil {
+				err = ctx.Err()
+			}
+		}()
+	}
+
+	enc, ok := reader.framer.(Encoder)
+	if !ok {
+		reader.log.Log(ctx, LevelTrace, "write to serial port", "direction", "tx", "data", hexdump(buffer))
+		return writer.Write(buffer)
+	}
+
+	frame := enc.Encode(buffer)
+	reader.log.Log(ctx, LevelTrace, "write to serial port", "direction", "tx", "data", hexdump(frame))
+	if n, err = writer.Write(frame); n < len(frame) {
+		// the number of bytes of buffer written is unknown
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+		return 0, err
+	}
+	return len(buffer), nil
+}
+
+// watchWrite interrupts a write blocked in wd by a write deadline in the past, when ctx is done or cancel is closed.
+// stop ends the watch and reports whether the write has been interrupted. In this case the write deadline is reset,
+// so the write deadline is only changed, if the write has been interrupted.
+func watchWrite(ctx context.Context, wd writeDeadliner, cancel <-chan struct{}) (stop func() bool) {
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-cancel:
+		case <-done:
+			interrupted <- false
+			return
+		}
+		// a fixed time in the past, because the clock of the reader may be a fake clock
+		wd.SetWriteDeadline(time.Unix(1, 0))
+		interrupted <- true
+	}()
+
+	return func() bool {
+		close(done)
+		if !<-interrupted {
+			return false
+		}
+		wd.SetWriteDeadline(time.Time{})
+		return true
+	}
 }
@@ -0,0 +1,101 @@
+package framereader
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy defines, which failed transactions are retried and the delay between the attempts.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts including the first one. If MaxAttempts is 0, no retry is made.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It's doubled for each further retry.
+	Backoff time.Duration
+
+	// MaxBackoff limits the delay between two attempts. If MaxBackoff is 0, the delay isn't limited.
+	MaxBackoff time.Duration
+
+	// Jitter randomizes the delay by the fraction Jitter, e.g. 0.2 for a delay between 80% and 120% of the backoff,
+	// so devices failed at the same time aren't retried at the same time.
+	Jitter float64
+
+	// RetryOnTimeout retries transactions failed by ErrTimeout.
+	RetryOnTimeout bool
+
+	// RetryOnChecksum retries transactions failed by ErrChecksum.
+	RetryOnChecksum bool
+
+	// RetryIf retries transactions, if it reports true. It receives the result of the transaction,
+	// so responses can be retried too, e.g. exception responses of a busy device.
+	RetryIf func(f Frame, err error) bool
+
+	// Clock is the source of time for the delay. If Clock is nil, the real time is used.
+	Clock Clock
+}
+
+// Retrier retries the failed transactions of a Transactor according to a RetryPolicy.
+// It implements Transactor, so it can be used by the clients of the protocol packages.
+type Retrier struct {
+	transactor Transactor
+	policy     RetryPolicy
+}
+
+// NewRetrier creates a retrier for the transactions of transactor, e.g. a *ReadWriteCloser or a *Bus.
+// If transactor is a Bus, the bus is released between the attempts.
+func NewRetrier(transactor Transactor, policy RetryPolicy) *Retrier {
+	if policy.Clock == nil {
+		policy.Clock = realClock{}
+	}
+	return &Retrier{transactor: transactor, policy: policy}
+}
+
+// Transact runs the transaction, until it succeeds, it isn't retried by the policy or the max attempts are reached.
+// The result of the last attempt is returned. If ctx is done while waiting for the next attempt,
+// ctx.Err() is returned.
+func (r *Retrier) Transact(ctx context.Context, request []byte, match func(Frame) bool) (Frame, error) {
+	for attempt := 1; ; attempt++ {
+		f, err := r.transactor.Transact(ctx, request, match)
+		if attempt >= r.policy.MaxAttempts || !r.retry(f, err) {
+			return f, err
+		}
+
+		timer := newTimer(r.policy.Clock, r.policy.delay(attempt, rand.Float64()))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return f, ctx.Err()
+		}
+	}
+}
+
+// retry reports whether the result of a transaction is retried.
+func (r *Retrier) retry(f Frame, err error) bool {
+	switch {
+	case r.policy.RetryIf != nil && r.policy.RetryIf(f, err):
+		return true
+	case err == nil:
+		return false
+	case r.policy.RetryOnTimeout && errors.Is(err, ErrTimeout):
+		return true
+	case r.policy.RetryOnChecksum && errors.Is(err, ErrChecksum):
+		return true
+	default:
+		return false
+	}
+}
+
+// delay returns the delay after attempt. random is a random number in [0, 1) used for the jitter.
+func (p RetryPolicy) delay(attempt int, random float64) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return time.Duration(float64(d) * (1 + p.Jitter*(2*random-1)))
+}
@@ -0,0 +1,114 @@
+package framereader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+// scriptedTransactor returns the results of results in turn.
+type scriptedTransactor struct {
+	results  []error
+	attempts int
+}
+
+func (t *scriptedTransactor) Transact(context.Context, []byte, func(Frame) bool) (Frame, error) {
+	err := t.results[t.attempts]
+	t.attempts++
+	return Frame{Data: []byte{byte(t.attempts)}}, err
+}
+
+func TestRetrier(t *testing.T) {
+	errOther := errors.New("other")
+
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		results  []error
+		attempts int
+		err      error
+	}{
+		{"success", RetryPolicy{MaxAttempts: 3, RetryOnTimeout: true}, []error{nil}, 1, nil},
+		{"timeout", RetryPolicy{MaxAttempts: 3, RetryOnTimeout: true}, []error{ErrTimeout, ErrTimeout, nil}, 3, nil},
+		{"max attempts", RetryPolicy{MaxAttempts: 2, RetryOnTimeout: true}, []error{ErrTimeout, ErrTimeout}, 2, ErrTimeout},
+		{"no timeout retry", RetryPolicy{MaxAttempts: 3, RetryOnChecksum: true}, []error{ErrTimeout}, 1, ErrTimeout},
+		{"checksum", RetryPolicy{MaxAttempts: 3, RetryOnChecksum: true}, []error{ErrChecksum, nil}, 2, nil},
+		{"other error", RetryPolicy{MaxAttempts: 3, RetryOnTimeout: true, RetryOnChecksum: true}, []error{errOther}, 1, errOther},
+		{"predicate", RetryPolicy{MaxAttempts: 3, RetryIf: func(f Frame, err error) bool { return f.Data[0] < 2 }}, []error{nil, nil}, 2, nil},
+		{"no retry", RetryPolicy{RetryOnTimeout: true}, []error{ErrTimeout}, 1, ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		transactor := &scriptedTransactor{results: tt.results}
+		_, err := NewRetrier(transactor, tt.policy).Transact(context.Background(), []byte{1}, MatchAll)
+		if err != tt.err || transactor.attempts != tt.attempts {
+			t.Errorf("%v: expected %v attempts with error %v, got %v attempts with error %v", tt.name, tt.attempts, tt.err, transactor.attempts, err)
+		}
+	}
+}
+
+func TestRetrierBackoff(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	transactor := &scriptedTransactor{results: []error{ErrTimeout, ErrTimeout, ErrTimeout, nil}}
+	retrier := NewRetrier(transactor, RetryPolicy{
+		MaxAttempts:    4,
+		Backoff:        100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		RetryOnTimeout: true,
+		Clock:          clock,
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := retrier.Transact(context.Background(), []byte{1}, MatchAll)
+		done <- err
+	}()
+
+	for _, d := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond} {
+		clock.BlockUntilTimer(d)
+		clock.Advance(d)
+	}
+	if err := <-done; err != nil || transactor.attempts != 4 {
+		t.Error("expected success after 4 attempts: ", transactor.attempts, err)
+	}
+
+	// the context is canceled while waiting for the next attempt
+	transactor = &scriptedTransactor{results: []error{ErrTimeout, nil}}
+	retrier = NewRetrier(transactor, RetryPolicy{MaxAttempts: 2, Backoff: time.Second, RetryOnTimeout: true, Clock: clock})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := retrier.Transact(ctx, []byte{1}, MatchAll)
+		done <- err
+	}()
+	clock.BlockUntilTimer(time.Second)
+	cancel()
+	if err := <-done; err != context.Canceled || transactor.attempts != 1 {
+		t.Error("expected canceled after 1 attempt: ", transactor.attempts, err)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: 0.2}
+
+	tests := []struct {
+		attempt int
+		random  float64
+		delay   time.Duration
+	}{
+		{1, 0.5, 100 * time.Millisecond},
+		{2, 0.5, 200 * time.Millisecond},
+		{5, 0.5, time.Second},
+		{50, 0.5, time.Second},
+		{1, 0, 80 * time.Millisecond},
+		{2, 1, 240 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if d := p.delay(tt.attempt, tt.random); d != tt.delay {
+			t.Errorf("attempt %v, random %v: expected %v, got %v", tt.attempt, tt.random, tt.delay, d)
+		}
+	}
+}
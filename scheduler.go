@@ -0,0 +1,275 @@
+package framereader
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// PollJob is a request sent periodically to a device by a Scheduler.
+type PollJob struct {
+	// Address identifies the device, e.g. the Modbus slave address.
+	// Jobs of the same address are skipped, while the device is offline.
+	Address int
+
+	// Request is the request sent to the device.
+	Request []byte
+
+	// Interval is the time between the starts of two polls.
+	Interval time.Duration
+
+	// Match selects the response to the request, see Transactor.
+	Match func(Frame) bool
+
+	// Handle receives the result of each poll, e.g. to parse the response.
+	// It's called by the goroutine running the scheduler, so the next poll is delayed, until Handle returns.
+	Handle func(PollResult)
+}
+
+// PollResult is the result of a poll passed to PollJob.Handle.
+type PollResult struct {
+	// Address is the address of the polled device.
+	Address int
+
+	// Frame is the response to the request.
+	Frame Frame
+
+	// Err is the error of the transaction.
+	Err error
+
+	// Scheduled is the time the poll was due.
+	Scheduled time.Time
+
+	// Missed is the number of polls skipped before this poll, because the scheduler was behind.
+	Missed int
+}
+
+// Scheduler runs poll jobs of many devices sharing one line, e.g. meters on a RS-485 bus.
+//
+// Only one poll runs at a time and the line is kept idle for the turnaround time between two polls.
+// Due polls are started in the order of their due time. If the line is overloaded,
+// polls, which are already overdue by a whole interval, are skipped instead of being queued,
+// so the scheduler catches up without a burst of requests and without an unbounded backlog.
+type Scheduler struct {
+	transactor Transactor
+	clock      Clock
+	turnaround time.Duration
+	jitter     float64
+	random     func() float64
+	health     *Health
+	wake       chan struct{} // signals a change of the polls or the offline devices to Run
+
+	mu      sync.Mutex   // guards the following fields
+	polls   []*poll      // in the order of addition
+	offline map[int]bool // addresses of the offline devices
+}
+
+// poll is the state of a PollJob of a Scheduler.
+type poll struct {
+	job    PollJob
+	due    time.Time // due time of the next poll
+	at     time.Time // start time of the next poll including the jitter
+	missed int       // polls skipped since the last poll
+}
+
+// SchedulerConfig is used to configure a Scheduler.
+type SchedulerConfig struct {
+	// Turnaround is the min time between the end of a transaction and the start of the next one,
+	// e.g. for slow devices or after broadcasts.
+	Turnaround time.Duration
+
+	// Jitter delays each poll by a random fraction of its interval up to Jitter, e.g. 0.1 for up to 10%,
+	// so the polls of jobs with the same interval are spread.
+	Jitter float64
+
+	// Health records the results of the polls. If Health is nil, the results aren't tracked.
+	// The jobs of devices, which are offline by Health, are only run once per probe interval, until the device responds again.
+	Health *Health
+
+	// Clock is the source of time for the schedule. If Clock is nil, the real time is used.
+	Clock Clock
+}
+
+// NewScheduler creates a scheduler running the polls by transactor, e.g. a *ReadWriteCloser or a *Bus.
+func NewScheduler(transactor Transactor) *Scheduler {
+	return NewSchedulerConfig(transactor, SchedulerConfig{})
+}
+
+// NewSchedulerConfig creates a scheduler using config.
+func NewSchedulerConfig(transactor Transactor, config SchedulerConfig) *Scheduler {
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+	return &Scheduler{
+		transactor: transactor,
+		clock:      config.Clock,
+		turnaround: config.Turnaround,
+		jitter:     config.Jitter,
+		random:     rand.Float64,
+		health:     config.Health,
+		offline:    map[int]bool{},
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Add adds job to the scheduler. The first poll is due immediately. Add can be called while the scheduler is running.
+// It panics, if the interval of job isn't positive.
+func (s *Scheduler) Add(job PollJob) {
+	if job.Interval <= 0 {
+		panic("framereader: non-positive poll interval")
+	}
+
+	s.mu.Lock()
+	p := &poll{job: job, due: s.clock.Now()}
+	p.at = p.due.Add(s.delay(job.Interval))
+	s.polls = append(s.polls, p)
+	s.mu.Unlock()
+	s.signal()
+}
+
+// SetOnline marks the device at address online or offline. The jobs of offline devices are skipped,
+// until the device is marked online again.
+func (s *Scheduler) SetOnline(address int, online bool) {
+	s.mu.Lock()
+	if online {
+		delete(s.offline, address)
+	} else {
+		s.offline[address] = true
+	}
+	s.mu.Unlock()
+	s.signal()
+}
+
+// Online reports whether the device at address is online. Devices are online, until they are marked offline.
+// The state of the Health of the scheduler isn't considered.
+func (s *Scheduler) Online(address int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.offline[address]
+}
+
+// Run runs the polls, until ctx is done. It returns ctx.Err().
+func (s *Scheduler) Run(ctx context.Context) error {
+	var last time.Time // end of the last transaction
+
+	for {
+		s.mu.Lock()
+		p := s.next()
+		s.mu.Unlock()
+
+		start, err := s.wait(ctx, p, last)
+		if err != nil {
+			return err
+		}
+		if !start {
+			// the polls have been changed meanwhile
+			continue
+		}
+
+		if s.run(ctx, p) {
+			last = s.clock.Now()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// wait waits, until the poll p may start after the end of the last transaction,
+// until the polls are changed or until ctx is done. If p is nil, it waits for a change of the polls.
+// It reports whether p may start.
+func (s *Scheduler) wait(ctx context.Context, p *poll, last time.Time) (bool, error) {
+	select {
+	case <-s.wake:
+		return false, nil
+	default:
+	}
+
+	var expired <-chan struct{}
+	if p != nil {
+		at := p.at
+		if t := last.Add(s.turnaround); !last.IsZero() && t.After(at) {
+			at = t
+		}
+		d := at.Sub(s.clock.Now())
+		if d <= 0 {
+			return true, nil
+		}
+		timer := newTimer(s.clock, d)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-expired:
+		return true, nil
+	case <-s.wake:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// run runs the poll p, if its device is online or due to be probed, and schedules the next poll.
+// It reports whether a transaction has been run.
+func (s *Scheduler) run(ctx context.Context, p *poll) bool {
+	s.mu.Lock()
+	online := !s.offline[p.job.Address]
+	s.mu.Unlock()
+	if online && s.health != nil {
+		online = s.health.probe(p.job.Address)
+	}
+
+	if online {
+		f, err := s.transactor.Transact(ctx, p.job.Request, p.job.Match)
+		if ctx.Err() == nil {
+			if s.health != nil && p.job.Match != nil {
+				s.health.Record(p.job.Address, err)
+			}
+			if p.job.Handle != nil {
+				p.job.Handle(PollResult{Address: p.job.Address, Frame: f, Err: err, Scheduled: p.due, Missed: p.missed})
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.missed = 0
+	p.due = p.due.Add(p.job.Interval)
+	if now := s.clock.Now(); !p.due.After(now) {
+		// only the latest overdue poll is started, the polls before are skipped
+		n := now.Sub(p.due) / p.job.Interval
+		p.due = p.due.Add(n * p.job.Interval)
+		if online {
+			p.missed = int(n)
+		}
+	}
+	p.at = p.due.Add(s.delay(p.job.Interval))
+	return online
+}
+
+// next returns the poll to start next or nil, if there is no poll. s.mu must be held.
+func (s *Scheduler) next() *poll {
+	var next *poll
+	for _, p := range s.polls {
+		if next == nil || p.at.Before(next.at) {
+			next = p
+		}
+	}
+	return next
+}
+
+// delay returns the random delay of a poll of interval.
+func (s *Scheduler) delay(interval time.Duration) time.Duration {
+	return time.Duration(s.jitter * s.random() * float64(interval))
+}
+
+// signal wakes up Run to reconsider the polls.
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,174 @@
+package framereader
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+// recordedPoll is a transaction recorded by recordingTransactor.
+type recordedPoll struct {
+	request byte
+	at      time.Duration
+}
+
+// recordingTransactor records the requests and the time of the transactions.
+// The first transaction lasts busy, i.e. it advances the clock by busy. All transactions fail by err.
+type recordingTransactor struct {
+	clock *framereadertest.Clock
+	busy  time.Duration
+
+	mu    sync.Mutex
+	err   error
+	polls []recordedPoll
+}
+
+func (t *recordingTransactor) Transact(_ context.Context, request []byte, _ func(Frame) bool) (Frame, error) {
+	t.mu.Lock()
+	t.polls = append(t.polls, recordedPoll{request[0], t.clock.Now().Sub(epoch)})
+	busy, err := t.busy, t.err
+	t.busy = 0
+	t.mu.Unlock()
+
+	t.clock.Advance(busy)
+	return Frame{Data: request}, err
+}
+
+// fail sets the error of the following transactions.
+func (t *recordingTransactor) fail(err error) {
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+}
+
+// runScheduler runs s, until the returned function is called, which checks the error of Run
+// and returns the recorded polls.
+func runScheduler(t *testing.T, s *Scheduler, transactor *recordingTransactor) func() []recordedPoll {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	return func() []recordedPoll {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Error("expected canceled, got: ", err)
+		}
+		transactor.mu.Lock()
+		defer transactor.mu.Unlock()
+		return transactor.polls
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	transactor := &recordingTransactor{clock: clock}
+	scheduler := NewSchedulerConfig(transactor, SchedulerConfig{Turnaround: 10 * time.Millisecond, Clock: clock})
+
+	results := make(chan PollResult, 10)
+	handle := func(r PollResult) { results <- r }
+	scheduler.Add(PollJob{Address: 1, Request: []byte{'a'}, Interval: 100 * time.Millisecond, Match: MatchAll, Handle: handle})
+	scheduler.Add(PollJob{Address: 2, Request: []byte{'b'}, Interval: 300 * time.Millisecond, Match: MatchAll, Handle: handle})
+	stop := runScheduler(t, scheduler, transactor)
+
+	for _, d := range []time.Duration{10, 90, 100, 100, 10} {
+		clock.BlockUntilTimer(d * time.Millisecond)
+		clock.Advance(d * time.Millisecond)
+	}
+	<-results
+	<-results
+	<-results
+	<-results
+	<-results
+	r := <-results
+
+	exp := []recordedPoll{{'a', 0}, {'b', 10 * time.Millisecond}, {'a', 100 * time.Millisecond}, {'a', 200 * time.Millisecond}, {'a', 300 * time.Millisecond}, {'b', 310 * time.Millisecond}}
+	if polls := stop(); !reflect.DeepEqual(polls, exp) {
+		t.Errorf("expected polls %v, got %v", exp, polls)
+	}
+	if r.Address != 2 || r.Frame.Data[0] != 'b' || r.Err != nil || !r.Scheduled.Equal(epoch.Add(300*time.Millisecond)) || r.Missed != 0 {
+		t.Error("unexpected result: ", r)
+	}
+}
+
+func TestSchedulerOffline(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	transactor := &recordingTransactor{clock: clock}
+	scheduler := NewSchedulerConfig(transactor, SchedulerConfig{Clock: clock})
+
+	scheduler.Add(PollJob{Address: 1, Request: []byte{'a'}, Interval: 100 * time.Millisecond, Match: MatchAll})
+	scheduler.Add(PollJob{Address: 2, Request: []byte{'b'}, Interval: 100 * time.Millisecond, Match: MatchAll})
+	scheduler.SetOnline(1, false)
+	if scheduler.Online(1) || !scheduler.Online(2) {
+		t.Error("expected device 1 offline and device 2 online")
+	}
+	stop := runScheduler(t, scheduler, transactor)
+
+	clock.BlockUntilTimer(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
+	clock.BlockUntilTimer(100 * time.Millisecond)
+	scheduler.SetOnline(1, true)
+	clock.BlockUntilTimer(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
+	clock.BlockUntilTimer(100 * time.Millisecond)
+
+	exp := []recordedPoll{{'b', 0}, {'b', 100 * time.Millisecond}, {'a', 200 * time.Millisecond}, {'b', 200 * time.Millisecond}}
+	if polls := stop(); !reflect.DeepEqual(polls, exp) {
+		t.Errorf("expected polls %v, got %v", exp, polls)
+	}
+}
+
+func TestSchedulerMissed(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	transactor := &recordingTransactor{clock: clock, busy: 250 * time.Millisecond}
+	scheduler := NewSchedulerConfig(transactor, SchedulerConfig{Clock: clock})
+
+	results := make(chan PollResult, 10)
+	scheduler.Add(PollJob{Address: 1, Request: []byte{'a'}, Interval: 100 * time.Millisecond, Match: MatchAll, Handle: func(r PollResult) { results <- r }})
+	stop := runScheduler(t, scheduler, transactor)
+
+	// the first poll lasts until 250ms, so the poll at 100ms is skipped and the poll at 200ms is late
+	clock.BlockUntilTimer(50 * time.Millisecond)
+	clock.Advance(50 * time.Millisecond)
+
+	for _, exp := range []struct {
+		scheduled time.Duration
+		missed    int
+	}{{0, 0}, {200 * time.Millisecond, 1}, {300 * time.Millisecond, 0}} {
+		r := <-results
+		if !r.Scheduled.Equal(epoch.Add(exp.scheduled)) || r.Missed != exp.missed {
+			t.Errorf("expected poll scheduled at %v with %v missed, got %v with %v missed", exp.scheduled, exp.missed, r.Scheduled.Sub(epoch), r.Missed)
+		}
+	}
+
+	exp := []recordedPoll{{'a', 0}, {'a', 250 * time.Millisecond}, {'a', 300 * time.Millisecond}}
+	if polls := stop(); !reflect.DeepEqual(polls, exp) {
+		t.Errorf("expected polls %v, got %v", exp, polls)
+	}
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	clock := framereadertest.NewClock(epoch)
+	transactor := &recordingTransactor{clock: clock}
+	scheduler := NewSchedulerConfig(transactor, SchedulerConfig{Jitter: 0.2, Clock: clock})
+	scheduler.random = func() float64 { return 0.5 }
+
+	scheduler.Add(PollJob{Address: 1, Request: []byte{'a'}, Interval: 100 * time.Millisecond, Match: MatchAll})
+	stop := runScheduler(t, scheduler, transactor)
+
+	clock.BlockUntilTimer(10 * time.Millisecond)
+	clock.Advance(10 * time.Millisecond)
+	clock.BlockUntilTimer(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
+	clock.BlockUntilTimer(100 * time.Millisecond)
+
+	exp := []recordedPoll{{'a', 10 * time.Millisecond}, {'a', 110 * time.Millisecond}}
+	if polls := stop(); !reflect.DeepEqual(polls, exp) {
+		t.Errorf("expected polls %v, got %v", exp, polls)
+	}
+}
@@ -0,0 +1,272 @@
+package sml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidTelegram is returned by Decode, if the telegram can't be parsed.
+var ErrInvalidTelegram = errors.New("sml: invalid telegram")
+
+// getListResponse is the message body tag of SML_GetList.Res, which contains the values of a meter.
+const getListResponse = 0x701
+
+// Types of the type-length field.
+const (
+	typeOctetString = 0x0
+	typeBoolean     = 0x4
+	typeInteger     = 0x5
+	typeUnsigned    = 0x6
+	typeList        = 0x7
+)
+
+// OBIS is the object identification of a value, e.g. 1-0:1.8.0*255 for the energy imported.
+type OBIS [6]byte
+
+// String returns the OBIS code in the format A-B:C.D.E*F.
+func (o OBIS) String() string {
+	return fmt.Sprintf("%v-%v:%v.%v.%v*%v", o[0], o[1], o[2], o[3], o[4], o[5])
+}
+
+// Unit is the DLMS unit code of a value.
+type Unit byte
+
+var unitText = map[Unit]string{
+	27: "W",
+	28: "VA",
+	29: "var",
+	30: "Wh",
+	31: "VAh",
+	32: "varh",
+	33: "A",
+	35: "V",
+	44: "Hz",
+}
+
+// String returns the symbol of the unit or the unit code, if the unit is unknown.
+func (u Unit) String() string {
+	if s, ok := unitText[u]; ok {
+		return s
+	}
+	return fmt.Sprintf("unit(%v)", byte(u))
+}
+
+// Entry is a value of a meter.
+type Entry struct {
+	OBIS   OBIS
+	Unit   Unit // 0, if the value has no unit
+	Scaler int8
+
+	// Value is int64, uint64, bool or []byte (e.g. the server id) according to the SML type.
+	Value interface{}
+}
+
+// Float returns the scaled value of a numeric entry. ok is false, if the value isn't numeric.
+func (e Entry) Float() (value float64, ok bool) {
+	switch v := e.Value.(type) {
+	case int64:
+		value = float64(v)
+	case uint64:
+		value = float64(v)
+	default:
+		return 0, false
+	}
+	return value * math.Pow10(int(e.Scaler)), true
+}
+
+// Decode returns the values of all list responses of telegram. The telegram is a frame returned by the framer
+// of NewFramer including the start and end sequences.
+func Decode(telegram []byte) ([]Entry, error) {
+	n := len(telegram) - 8
+	if n < len(startSequence) || !bytes.HasPrefix(telegram, startSequence) {
+		return nil, ErrInvalidTelegram
+	}
+	padding := int(telegram[n+5])
+
+	// unescape the messages between start and end sequence, escape sequences are aligned to 4 bytes
+	var data []byte
+	body := telegram[len(startSequence):n]
+	for i := 0; i < len(body); i += 4 {
+		chunk := body[i:min(i+4, len(body))]
+		data = append(data, chunk...)
+		if bytes.Equal(chunk, escapeSequence) {
+			i += 4
+		}
+	}
+	if padding > len(data) {
+		return nil, ErrInvalidTelegram
+	}
+	p := parser{data: data[:len(data)-padding]}
+
+	var entries []Entry
+	for p.pos < len(p.data) {
+		if p.data[p.pos] == 0x00 {
+			// end of message or padding
+			p.pos++
+			continue
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		message, ok := v.([]interface{})
+		if !ok || len(message) != 6 {
+			return nil, ErrInvalidTelegram
+		}
+
+		body, ok := message[3].([]interface{})
+		if !ok || len(body) != 2 {
+			return nil, ErrInvalidTelegram
+		}
+		if tag, _ := body[0].(uint64); tag != getListResponse {
+			continue
+		}
+
+		e, err := listEntries(body[1])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+// listEntries returns the values of the valList of a SML_GetList.Res.
+func listEntries(response interface{}) ([]Entry, error) {
+	r, ok := response.([]interface{})
+	if !ok || len(r) != 7 {
+		return nil, ErrInvalidTelegram
+	}
+	list, ok := r[4].([]interface{})
+	if !ok {
+		return nil, ErrInvalidTelegram
+	}
+
+	entries := make([]Entry, 0, len(list))
+	for _, v := range list {
+		l, ok := v.([]interface{})
+		if !ok || len(l) != 7 {
+			return nil, ErrInvalidTelegram
+		}
+		name, ok := l[0].([]byte)
+		if !ok || len(name) != len(OBIS{}) {
+			return nil, ErrInvalidTelegram
+		}
+
+		var e Entry
+		copy(e.OBIS[:], name)
+		if unit, ok := l[3].(uint64); ok {
+			e.Unit = Unit(unit)
+		}
+		if scaler, ok := l[4].(int64); ok {
+			e.Scaler = int8(scaler)
+		}
+		e.Value = l[5]
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// parser decodes the type-length-value encoding of SML.
+type parser struct {
+	data []byte
+	pos  int
+}
+
+// value returns the next value: nil for an omitted optional value or the end of message, []interface{} for a list,
+// []byte, bool, int64 or uint64.
+func (p *parser) value() (interface{}, error) {
+	if p.pos < len(p.data) && p.data[p.pos] == 0x00 {
+		// end of message
+		p.pos++
+		return nil, nil
+	}
+
+	start := p.pos
+	typ, length, err := p.typeLength()
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == typeList {
+		// each element takes one byte at least
+		if length > len(p.data)-p.pos {
+			return nil, ErrInvalidTelegram
+		}
+		list := make([]interface{}, length)
+		for i := range list {
+			if list[i], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+
+	// the length of other types includes the type-length field
+	size := length - (p.pos - start)
+	if size < 0 || p.pos+size > len(p.data) {
+		return nil, ErrInvalidTelegram
+	}
+	data := p.data[p.pos : p.pos+size]
+	p.pos += size
+
+	if size == 0 && typ == typeOctetString && length == 1 {
+		// omitted optional value
+		return nil, nil
+	}
+
+	switch typ {
+	case typeOctetString:
+		return data, nil
+	case typeBoolean:
+		if size != 1 {
+			return nil, ErrInvalidTelegram
+		}
+		return data[0] != 0, nil
+	case typeInteger, typeUnsigned:
+		if size < 1 || size > 8 {
+			return nil, ErrInvalidTelegram
+		}
+		var u uint64
+		for _, b := range data {
+			u = u<<8 | uint64(b)
+		}
+		if typ == typeUnsigned {
+			return u, nil
+		}
+		// sign extension
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, nil
+	default:
+		return nil, ErrInvalidTelegram
+	}
+}
+
+// typeLength returns type and length of the next type-length field. A field may span multiple bytes,
+// each continuation adds 4 bits to the length. Lengths exceeding the data are invalid,
+// so the length can't overflow.
+func (p *parser) typeLength() (typ byte, length int, err error) {
+	if p.pos >= len(p.data) {
+		return 0, 0, ErrInvalidTelegram
+	}
+	b := p.data[p.pos]
+	p.pos++
+	typ = b >> 4 & 0x7
+	length = int(b & 0xf)
+
+	for b&0x80 != 0 {
+		if p.pos >= len(p.data) {
+			return 0, 0, ErrInvalidTelegram
+		}
+		if length > len(p.data) {
+			return 0, 0, ErrInvalidTelegram
+		}
+		b = p.data[p.pos]
+		p.pos++
+		length = length<<4 | int(b&0xf)
+	}
+	return typ, length, nil
+}
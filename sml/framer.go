@@ -0,0 +1,126 @@
+// Package sml reads the telegrams of the Smart Message Language (SML) pushed by electricity meters.
+//
+// The telegrams are framed by the SML transport protocol version 1: they start with the escape sequence
+// 1b1b1b1b 01010101 and end with 1b1b1b1b 1a, followed by the number of padding bytes and the CRC-16/X-25.
+// Escape sequences in the data are doubled.
+package sml
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/womat/framereader"
+	"github.com/womat/framereader/internal/crc"
+)
+
+// MaxTelegramSize is the max frame size configured by WithFramer. It's sufficient for the telegrams of common meters.
+const MaxTelegramSize = 4096
+
+var (
+	escapeSequence = []byte{0x1b, 0x1b, 0x1b, 0x1b}
+	startSequence  = []byte{0x1b, 0x1b, 0x1b, 0x1b, 0x01, 0x01, 0x01, 0x01}
+)
+
+// endMarker follows the escape sequence at the end of a telegram.
+const endMarker = 0x1a
+
+// framer reassembles SML telegrams.
+type framer struct {
+	policy framereader.ChecksumPolicy
+}
+
+// NewFramer creates a framer for SML telegrams. The frames include the start and end sequences.
+// Data received outside of telegrams is dropped. The inter frame delay is ignored,
+// so telegrams are reassembled regardless of the chunks received.
+func NewFramer(policy framereader.ChecksumPolicy) framereader.Framer {
+	return framer{policy: policy}
+}
+
+// WithFramer configures the reader for SML telegrams by NewFramer and a max frame size of MaxTelegramSize.
+func WithFramer(policy framereader.ChecksumPolicy) framereader.Option {
+	return func(c *framereader.Config) {
+		c.Framer = NewFramer(policy)
+		c.MaxFrameSize = MaxTelegramSize
+	}
+}
+
+// Split returns the next telegram, if the end sequence is received.
+func (f framer) Split(data []byte, _ bool) (int, []byte, error) {
+	i := bytes.Index(data, startSequence)
+	if i < 0 {
+		// drop the data, but keep a partial start sequence
+		if n := len(data) - len(startSequence) + 1; n > 0 {
+			return n, nil, nil
+		}
+		return 0, nil, nil
+	}
+	if i > 0 {
+		return i, nil, nil
+	}
+
+	// a telegram interrupted by the next one is dropped, if the end sequence isn't found before the next start sequence
+	end := len(data)
+	if next := nextStart(data); next >= 0 {
+		end = next
+	}
+
+	// escape sequences are aligned to 4 bytes, the telegram is padded accordingly
+	for pos := len(startSequence); pos+8 <= end; pos += 4 {
+		if !bytes.Equal(data[pos:pos+4], escapeSequence) {
+			continue
+		}
+
+		switch next := data[pos+4 : pos+8]; {
+		case bytes.Equal(next, escapeSequence):
+			// escaped data
+			pos += 4
+		case bytes.Equal(next, startSequence[4:]):
+			// the telegram is interrupted by the next one
+			return pos, nil, nil
+		case next[0] == endMarker:
+			n := pos + 8
+			if validTelegram(data[:n]) {
+				return n, data[:n], nil
+			}
+			if f.policy == framereader.ChecksumError {
+				return n, data[:n], framereader.ErrChecksum
+			}
+			return n, nil, nil
+		default:
+			// invalid escape sequence
+			return pos + 8, nil, nil
+		}
+	}
+	if end < len(data) {
+		return end, nil, nil
+	}
+	return 0, nil, nil
+}
+
+// Resync returns 0, because the data up to the next start sequence is dropped by Split.
+// It implements framereader.Resyncer, so an oversized telegram doesn't cause the next telegram to be dropped.
+func (framer) Resync([]byte) int {
+	return 0
+}
+
+// nextStart returns the index of the first start sequence following the start of the telegram,
+// which isn't aligned to 4 bytes, or -1. Aligned start sequences are detected by Split,
+// because an escaped escape sequence followed by 01010101 looks like a start sequence.
+func nextStart(data []byte) int {
+	for i := len(startSequence); ; {
+		next := bytes.Index(data[i:], startSequence)
+		if next < 0 {
+			return -1
+		}
+		if i += next; i%4 != 0 {
+			return i
+		}
+		i++
+	}
+}
+
+// validTelegram reports whether the CRC of telegram is valid. The CRC is transmitted low byte first.
+func validTelegram(telegram []byte) bool {
+	n := len(telegram) - 2
+	return crc.X25(telegram[:n]) == binary.LittleEndian.Uint16(telegram[n:])
+}
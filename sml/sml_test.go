@@ -0,0 +1,188 @@
+package sml
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/womat/framereader"
+	"github.com/womat/framereader/internal/crc"
+)
+
+// The following helpers encode SML values for the tests.
+
+func octets(data ...byte) []byte {
+	return append([]byte{byte(len(data) + 1)}, data...)
+}
+
+func unsigned(v uint64, size int) []byte {
+	b := []byte{byte(typeUnsigned<<4 | size + 1)}
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+func integer(v int64, size int) []byte {
+	b := unsigned(uint64(v), size)
+	b[0] = byte(typeInteger<<4 | size + 1)
+	return b
+}
+
+func list(values ...[]byte) []byte {
+	return append([]byte{byte(typeList<<4 | len(values))}, bytes.Join(values, nil)...)
+}
+
+var optional = []byte{0x01}
+
+func message(tag uint64, body []byte) []byte {
+	return list(octets(1), unsigned(0, 1), unsigned(0, 1), list(unsigned(tag, 4), body), unsigned(0, 2), []byte{0x00})
+}
+
+// telegram escapes messages and adds start and end sequences and the CRC.
+func telegram(messages ...[]byte) []byte {
+	data := bytes.Join(messages, nil)
+	padding := (4 - len(data)%4) % 4
+	data = append(data, make([]byte, padding)...)
+
+	t := append([]byte(nil), startSequence...)
+	for i := 0; i < len(data); i += 4 {
+		t = append(t, data[i:i+4]...)
+		if bytes.Equal(data[i:i+4], escapeSequence) {
+			t = append(t, escapeSequence...)
+		}
+	}
+	t = append(t, 0x1b, 0x1b, 0x1b, 0x1b, endMarker, byte(padding))
+	return binary.LittleEndian.AppendUint16(t, crc.X25(t))
+}
+
+// meterTelegram returns a telegram with an open response and a list response containing the energy and power.
+// The server id contains an escape sequence.
+func meterTelegram() []byte {
+	serverID := bytes.Repeat([]byte{0x1b}, 8)
+	entry := func(obis []byte, unit uint64, scaler int64, value []byte) []byte {
+		return list(octets(obis...), optional, optional, unsigned(unit, 1), integer(scaler, 1), value, optional)
+	}
+
+	return telegram(
+		message(0x101, list(optional, octets(1, 2), octets(3, 4), octets(serverID...), optional, optional)),
+		message(getListResponse, list(optional, octets(serverID...), optional, optional, list(
+			entry([]byte{1, 0, 1, 8, 0, 255}, 30, -1, unsigned(123456789, 8)),
+			entry([]byte{1, 0, 16, 7, 0, 255}, 27, 0, integer(-250, 4)),
+			list(octets(129, 129, 199, 130, 3, 255), optional, optional, optional, optional, octets('A', 'B', 'C'), optional),
+		), optional, optional)),
+	)
+}
+
+func TestDecode(t *testing.T) {
+	entries, err := Decode(meterTelegram())
+	if err != nil {
+		t.Fatal("decode failed: ", err)
+	}
+
+	exp := []Entry{
+		{OBIS{1, 0, 1, 8, 0, 255}, 30, -1, uint64(123456789)},
+		{OBIS{1, 0, 16, 7, 0, 255}, 27, 0, int64(-250)},
+		{OBIS{129, 129, 199, 130, 3, 255}, 0, 0, []byte("ABC")},
+	}
+	if !reflect.DeepEqual(entries, exp) {
+		t.Errorf("expected %v, got %v", exp, entries)
+	}
+
+	if s := entries[0].OBIS.String(); s != "1-0:1.8.0*255" {
+		t.Error("unexpected OBIS code: ", s)
+	}
+	if v, ok := entries[0].Float(); !ok || v != 12345678.9 {
+		t.Error("expected scaled value 12345678.9: ", v)
+	}
+	if entries[1].Unit.String() != "W" {
+		t.Error("expected unit W: ", entries[1].Unit)
+	}
+	if _, ok := entries[2].Float(); ok {
+		t.Error("expected octet string not to be numeric")
+	}
+
+	if _, err = Decode(telegram(list(octets(1), unsigned(0, 1)))); err != ErrInvalidTelegram {
+		t.Error("expected invalid telegram, got: ", err)
+	}
+}
+
+func TestDecodeInvalidLength(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"overflow", append(append([]byte{0xff}, bytes.Repeat([]byte{0x8f}, 15)...), 0x0f, 0x01)},
+		{"list exceeding data", []byte{0xf7, 0x0f, 0x01, 0x01}},
+		{"octet string exceeding data", []byte{0x8f, 0x8f, 0x0f, 0x01}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Decode(telegram(tt.data)); err != ErrInvalidTelegram {
+			t.Errorf("%v: expected invalid telegram, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestFramer(t *testing.T) {
+	valid := meterTelegram()
+	invalid := append([]byte(nil), valid...)
+	invalid[20]++
+
+	var data []byte
+	data = append(data, 0x1b, 0x1b, 0x01, 0x02) // garbage
+	data = append(data, valid...)
+	data = append(data, invalid...)
+	data = append(data, valid[:30]...) // interrupted telegrams
+	data = append(data, valid[:32]...)
+	data = append(data, valid...)
+
+	reader := framereader.New(iotest.OneByteReader(bytes.NewReader(data)), framereader.WithTimeout(time.Second), WithFramer(framereader.ChecksumDrop)).(*framereader.Reader)
+
+	for i := 0; i < 2; i++ {
+		f, err := reader.ReadFrame()
+		if err != nil || !bytes.Equal(f.Data, valid) {
+			t.Errorf("expected telegram %v, got %v bytes (err: %v)", i, len(f.Data), err)
+		}
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Error("expected EOF, got: ", err)
+	}
+}
+
+func TestFramerOversized(t *testing.T) {
+	valid := meterTelegram()
+	oversized := telegram(bytes.Repeat([]byte{0x42}, 2*len(valid)))
+	data := append(append([]byte(nil), oversized...), valid...)
+
+	reader := framereader.New(iotest.OneByteReader(bytes.NewReader(data)),
+		framereader.WithTimeout(time.Second),
+		WithFramer(framereader.ChecksumDrop),
+		framereader.WithMaxFrameSize(len(valid)),
+	).(*framereader.Reader)
+
+	if f, err := reader.ReadFrame(); err != nil || !f.Truncated || !bytes.Equal(f.Data, oversized[:len(valid)]) {
+		t.Errorf("expected truncated telegram, got %v bytes (truncated: %v, err: %v)", len(f.Data), f.Truncated, err)
+	}
+	if f, err := reader.ReadFrame(); err != nil || !bytes.Equal(f.Data, valid) {
+		t.Errorf("expected telegram following the oversized one, got %v bytes (err: %v)", len(f.Data), err)
+	}
+}
+
+func TestFramerChecksumError(t *testing.T) {
+	invalid := meterTelegram()
+	invalid[20]++
+
+	advance, frame, err := NewFramer(framereader.ChecksumError).Split(invalid, false)
+	if advance != len(invalid) || !bytes.Equal(frame, invalid) || err != framereader.ErrChecksum {
+		t.Errorf("expected checksum error, got %v, %v bytes, %v", advance, len(frame), err)
+	}
+
+	if advance, frame, err = NewFramer(framereader.ChecksumError).Split(invalid[:len(invalid)-1], false); advance != 0 || frame != nil || err != nil {
+		t.Errorf("expected framer to wait for the end of the telegram, got %v, %v, %v", advance, frame, err)
+	}
+}
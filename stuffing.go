@@ -0,0 +1,131 @@
+package framereader
+
+import (
+	"bytes"
+)
+
+// Encoder is implemented by framers of byte stuffed protocols.
+// The data written by ReadWriter and ReadWriteCloser is encoded as a frame by Encode,
+// if the framer of the reader implements Encoder.
+// A frame exceeding the max frame size before its end is received is delivered without data, because it can't be decoded.
+type Encoder interface {
+	Encode(frame []byte) []byte
+}
+
+// Special characters of SLIP.
+const (
+	slipEnd    = 0xc0
+	slipEsc    = 0xdb
+	slipEscEnd = 0xdc
+	slipEscEsc = 0xdd
+)
+
+// slipFramer decodes and encodes SLIP frames.
+type slipFramer struct{}
+
+// NewSLIPFramer creates a framer for the Serial Line Internet Protocol (RFC 1055).
+// Frames end with END (0xc0), END and ESC (0xdb) in the data are escaped.
+// The frames are decoded on Read and encoded on Write. The inter frame delay is ignored.
+func NewSLIPFramer() Framer {
+	return slipFramer{}
+}
+
+// Split returns the decoded data up to the next END. Empty frames are dropped.
+func (slipFramer) Split(data []byte, _ bool) (int, []byte, error) {
+	i := bytes.IndexByte(data, slipEnd)
+	if i < 0 {
+		return 0, nil, nil
+	}
+	if i == 0 {
+		return 1, nil, nil
+	}
+
+	frame := make([]byte, 0, i)
+	for j := 0; j < i; j++ {
+		b := data[j]
+		if b == slipEsc && j+1 < i {
+			j++
+			switch data[j] {
+			case slipEscEnd:
+				b = slipEnd
+			case slipEscEsc:
+				b = slipEsc
+			default:
+				// protocol violation, the byte is passed as recommended by RFC 1055
+				b = data[j]
+			}
+		}
+		frame = append(frame, b)
+	}
+	return i + 1, frame, nil
+}
+
+// Encode returns the SLIP frame of data. The frame starts with END to flush noise received by the peer.
+func (slipFramer) Encode(data []byte) []byte {
+	frame := make([]byte, 0, len(data)+2)
+	frame = append(frame, slipEnd)
+	for _, b := range data {
+		switch b {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, slipEnd)
+}
+
+// cobsFramer decodes and encodes COBS frames.
+type cobsFramer struct{}
+
+// NewCOBSFramer creates a framer for Consistent Overhead Byte Stuffing. Frames are encoded without zero bytes
+// and end with a zero byte. The frames are decoded on Read and encoded on Write. The inter frame delay is ignored.
+// Frames, which can't be decoded, are returned undecoded with ErrEncoding.
+func NewCOBSFramer() Framer {
+	return cobsFramer{}
+}
+
+// Split returns the decoded data up to the next zero byte. Empty frames are dropped.
+func (cobsFramer) Split(data []byte, _ bool) (int, []byte, error) {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return 0, nil, nil
+	}
+	if i == 0 {
+		return 1, nil, nil
+	}
+
+	frame := make([]byte, 0, i)
+	for j := 0; j < i; {
+		code := int(data[j])
+		if j+code > i {
+			return i + 1, data[:i], ErrEncoding
+		}
+		frame = append(frame, data[j+1:j+code]...)
+		j += code
+		if code < 0xff && j < i {
+			frame = append(frame, 0)
+		}
+	}
+	return i + 1, frame, nil
+}
+
+// Encode returns the COBS frame of data including the trailing zero byte.
+func (cobsFramer) Encode(data []byte) []byte {
+	frame := make([]byte, 1, len(data)+len(data)/254+2)
+	code := 0 // index of the current code byte
+	for _, b := range data {
+		if b != 0 {
+			frame = append(frame, b)
+		}
+		if b == 0 || len(frame)-code == 0xff {
+			frame[code] = byte(len(frame) - code)
+			code = len(frame)
+			frame = append(frame, 0)
+		}
+	}
+	frame[code] = byte(len(frame) - code)
+	return append(frame, 0)
+}
@@ -0,0 +1,107 @@
+package framereader
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+func TestSLIPFramer(t *testing.T) {
+	framer := NewSLIPFramer()
+
+	tests := []struct {
+		data    []byte
+		encoded []byte
+	}{
+		{[]byte{1, 2, 3}, []byte{0xc0, 1, 2, 3, 0xc0}},
+		{[]byte{0xc0, 0xdb, 0xdc}, []byte{0xc0, 0xdb, 0xdc, 0xdb, 0xdd, 0xdc, 0xc0}},
+	}
+
+	for _, tt := range tests {
+		encoded := framer.(Encoder).Encode(tt.data)
+		if !bytes.Equal(encoded, tt.encoded) {
+			t.Errorf("%v: expected encoding % x, got % x", tt.data, tt.encoded, encoded)
+		}
+
+		// the leading END is an empty frame
+		advance, frame, err := framer.Split(encoded, false)
+		if advance != 1 || frame != nil || err != nil {
+			t.Errorf("%v: expected empty frame to be dropped, got %v, %v, %v", tt.data, advance, frame, err)
+		}
+		advance, frame, err = framer.Split(encoded[1:], false)
+		if advance != len(encoded)-1 || !bytes.Equal(frame, tt.data) || err != nil {
+			t.Errorf("%v: unexpected decoding %v, % x, %v", tt.data, advance, frame, err)
+		}
+	}
+
+	if advance, frame, _ := framer.Split([]byte{1, 0xdb, 5, 0xc0}, false); advance != 4 || !bytes.Equal(frame, []byte{1, 5}) {
+		t.Errorf("expected invalid escape to be passed, got %v, % x", advance, frame)
+	}
+	if advance, frame, _ := framer.Split([]byte{1, 2}, true); advance != 0 || frame != nil {
+		t.Errorf("expected framer to wait for END, got %v, % x", advance, frame)
+	}
+}
+
+func TestCOBSFramer(t *testing.T) {
+	framer := NewCOBSFramer()
+	long := bytes.Repeat([]byte{0x11}, 300)
+
+	tests := []struct {
+		data    []byte
+		encoded []byte
+	}{
+		{[]byte{0}, []byte{1, 1, 0}},
+		{[]byte{0, 0}, []byte{1, 1, 1, 0}},
+		{[]byte{0x11, 0x22, 0, 0x33}, []byte{3, 0x11, 0x22, 2, 0x33, 0}},
+		{[]byte{0x11, 0, 0, 0}, []byte{2, 0x11, 1, 1, 1, 0}},
+		{long, append(append(append([]byte{0xff}, long[:254]...), 47), append(long[254:], 0)...)},
+	}
+
+	for _, tt := range tests {
+		encoded := framer.(Encoder).Encode(tt.data)
+		if !bytes.Equal(encoded, tt.encoded) {
+			t.Errorf("% x: expected encoding % x, got % x", tt.data, tt.encoded, encoded)
+		}
+
+		advance, frame, err := framer.Split(encoded, false)
+		if advance != len(encoded) || !bytes.Equal(frame, tt.data) || err != nil {
+			t.Errorf("% x: unexpected decoding %v, % x, %v", tt.data, advance, frame, err)
+		}
+	}
+
+	if advance, frame, err := framer.Split([]byte{5, 1, 0, 2}, false); advance != 3 || !bytes.Equal(frame, []byte{5, 1}) || err != ErrEncoding {
+		t.Errorf("expected encoding error, got %v, % x, %v", advance, frame, err)
+	}
+	if advance, frame, err := framer.Split([]byte{0, 2, 1}, false); advance != 1 || frame != nil || err != nil {
+		t.Errorf("expected empty frame to be dropped, got %v, % x, %v", advance, frame, err)
+	}
+}
+
+func TestReadWriterEncoder(t *testing.T) {
+	port := framereadertest.NewPort()
+	rw := NewReadWriterConfig(port, Config{Timeout: time.Second, InterFrameDelay: 10 * time.Millisecond, Framer: NewCOBSFramer()})
+	defer rw.reader.Close()
+	defer port.Close()
+
+	if n, err := rw.Write([]byte{0x11, 0, 0x22}); n != 3 || err != nil {
+		t.Error("write failed: ", n, err)
+	}
+	if exp := [][]byte{{2, 0x11, 2, 0x22, 0}}; !reflect.DeepEqual(port.Written(), exp) {
+		t.Errorf("expected encoded frame % x, got % x", exp, port.Written())
+	}
+
+	// the response is decoded regardless of the chunks received
+	port.Send([]byte{3, 0x33})
+	port.Send([]byte{0x44, 1, 0, 2, 0x55, 0})
+
+	for _, exp := range [][]byte{{0x33, 0x44, 0}, {0x55}} {
+		data := make([]byte, 10)
+		n, err := rw.Read(data)
+		if err != nil || !bytes.Equal(data[:n], exp) {
+			t.Errorf("expected % x, got % x (err: %v)", exp, data[:n], err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package framereader
+
+import (
+	"context"
+	"io"
+)
+
+// Transactor sends a request and returns the matching response.
+// It's implemented by ReadWriter and ReadWriteCloser.
+type Transactor interface {
+	// Transact writes request and reads frames, until a frame satisfies match.
+	// If match is nil, Transact returns after request is written, e.g. for broadcasts without response.
+	Transact(ctx context.Context, request []byte, match func(Frame) bool) (Frame, error)
+}
+
+// MatchAll is a matcher for Transact, which accepts the first frame received.
+func MatchAll(Frame) bool {
+	return true
+}
+
+// transact flushes all data from reader, writes request to writer and reads frames from reader,
+// until a frame satisfies match. Unrelated frames are discarded.
+// The overall timeout of reader applies to the whole transaction including the flush.
+func transact(ctx context.Context, reader *Reader, writer io.Writer, d *deadline, request []byte, match func(Frame) bool) (Frame, error) {
+	timeout := newTimer(reader.clock, reader.timeout)
+	defer timeout.Stop()
+
+	if _, err := writeContext(ctx, reader, writer, d, timeout.C, request); err != nil {
+		return Frame{}, err
+	}
+	if match == nil {
+		return Frame{}, nil
+	}
+
+	for {
+		f, err := reader.readFrame(ctx, timeout.C)
+		if err != nil || match(f) {
+			return f, err
+		}
+		reader.log.Debug("discard unrelated frame", "direction", "rx", "data", hexdump(f.Data))
+	}
+}
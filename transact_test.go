@@ -0,0 +1,131 @@
+package framereader
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/womat/framereader/framereadertest"
+)
+
+// device answers each request with the responses returned by respond. The responses are separated by a delay.
+func device(conn net.Conn, respond func(request []byte) [][]byte) {
+	buffer := make([]byte, 64)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return
+		}
+		for _, r := range respond(append([]byte(nil), buffer[:n]...)) {
+			time.Sleep(15 * time.Millisecond)
+			conn.Write(r)
+		}
+	}
+}
+
+// matchFirst returns a matcher for frames starting with b.
+func matchFirst(b byte) func(Frame) bool {
+	return func(f Frame) bool {
+		return len(f.Data) > 0 && f.Data[0] == b
+	}
+}
+
+func TestTransact(t *testing.T) {
+	master, slave := net.Pipe()
+	defer slave.Close()
+	rwc := NewReadWriteCloserConfig(master, Config{Timeout: 200 * time.Millisecond, InterFrameDelay: 5 * time.Millisecond})
+	defer rwc.Close()
+
+	go device(slave, func(request []byte) [][]byte {
+		switch request[0] {
+		case 1:
+			// an unrelated frame is received before the response
+			return [][]byte{{9, 9}, {1, 42}}
+		case 2:
+			return [][]byte{{9, 9}}
+		}
+		return nil
+	})
+
+	f, err := rwc.Transact(context.Background(), []byte{1}, matchFirst(1))
+	if err != nil || string(f.Data) != string([]byte{1, 42}) {
+		t.Errorf("expected response, got %v (err: %v)", f.Data, err)
+	}
+
+	start := time.Now()
+	if _, err = rwc.Transact(context.Background(), []byte{2}, matchFirst(2)); err != ErrTimeout {
+		t.Error("expected timeout, got: ", err)
+	}
+	if d := time.Since(start); d > 400*time.Millisecond {
+		t.Error("expected overall timeout for the transaction, took: ", d)
+	}
+
+	if _, err = rwc.Transact(context.Background(), []byte{3}, nil); err != nil {
+		t.Error("expected request without response, got: ", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = rwc.Transact(ctx, []byte{4}, MatchAll); err != context.DeadlineExceeded {
+		t.Error("expected context deadline, got: ", err)
+	}
+}
+
+func TestTransactBusyLine(t *testing.T) {
+	port := framereadertest.NewPort()
+	rwc := NewReadWriteCloserConfig(port, Config{Timeout: 200 * time.Millisecond, InterFrameDelay: 50 * time.Millisecond, Framer: NewLengthPrefixFramer(1)})
+	defer rwc.Close()
+
+	// frames are received continuously, so the flush before the request doesn't end
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(2 * time.Millisecond):
+				port.Send([]byte{0})
+			}
+		}
+	}()
+
+	start := time.Now()
+	if _, err := rwc.Transact(context.Background(), []byte{1}, MatchAll); err != ErrTimeout {
+		t.Error("expected timeout, got: ", err)
+	}
+	if d := time.Since(start); d > 600*time.Millisecond {
+		t.Error("expected overall timeout to include the flush, took: ", d)
+	}
+	if written := port.Written(); written != nil {
+		t.Error("expected no request to be written: ", written)
+	}
+}
+
+func TestTransactConcurrent(t *testing.T) {
+	master, slave := net.Pipe()
+	defer slave.Close()
+	rw := NewReadWriterConfig(master, Config{Timeout: time.Second, InterFrameDelay: 5 * time.Millisecond})
+	defer rw.reader.Close()
+
+	go device(slave, func(request []byte) [][]byte {
+		return [][]byte{{request[0], 0xff}}
+	})
+
+	var wg sync.WaitGroup
+	for i := byte(1); i <= 4; i++ {
+		wg.Add(1)
+		go func(id byte) {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				f, err := rw.Transact(context.Background(), []byte{id}, matchFirst(id))
+				if err != nil || f.Data[0] != id {
+					t.Errorf("%v: expected own response, got %v (err: %v)", id, f.Data, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}